@@ -3,6 +3,7 @@ package backport
 import (
 	"context"
 	"fmt"
+	"maps"
 	"slices"
 	"strings"
 
@@ -12,13 +13,31 @@ import (
 	"github.com/yhabteab/backbot/github"
 )
 
+// targetHistoryDepth is the number of the most recent commits of the target branches that are fetched
+// and searched for commits which have already been backported.
+const targetHistoryDepth = 100
+
 // backPorter handles the backporting of pull requests to specified branches.
 type backPorter struct {
-	github *github.Client // GitHub client for API interactions
+	github github.API // GitHub client for API interactions
 
 	git *git.Git // Git client for git cli operations
 
 	config *Input // Configuration inputs for the backporting process
+
+	request *Request // Explicit backport request, if not triggered by a GitHub event
+
+	report *report // Collects the results of the current run for the job summary
+
+	plan *dryRunPlan // Collects the planned actions in dry-run mode, nil otherwise
+
+	remoteBranches []string // The branches of the remote, listed when supported branches are first requested
+}
+
+// Request describes an explicit backport request that doesn't originate from a GitHub event payload.
+type Request struct {
+	PrNumber int64    // The number of the pull request to backport.
+	Branches []string // The target branches to backport the pull request to.
 }
 
 // Run is the entry point for the backporting process.
@@ -27,24 +46,106 @@ type backPorter struct {
 // and invokes the Run method to perform the backporting. If any error occurs during
 // the process, it logs a fatal error and exits with a non-zero status code.
 func Run(ctx context.Context, cfg *Input, ghCtx *githubactions.GitHubContext) {
-	b := &backPorter{
-		github: github.NewClient(ghCtx, cfg.GitHubToken),
-		git:    git.NewGit(ghCtx),
-		config: cfg,
-	}
-	if err := b.Run(ctx); err != nil {
+	RunRequest(ctx, cfg, ghCtx, nil)
+}
+
+// RunRequest is like [Run], but backports the pull request to the branches of the given request
+// instead of determining them from the event that triggered the workflow.
+//
+// A nil request is equivalent to calling [Run].
+func RunRequest(ctx context.Context, cfg *Input, ghCtx *githubactions.GitHubContext, req *Request) {
+	if err := runRequest(ctx, cfg, ghCtx, req); err != nil {
 		githubactions.Fatalf("Backport failed: %v", err)
 	}
 }
 
+// runRequest sets up a backPorter authenticated as configured and runs it for the given request.
+//
+// If backbot authenticates as a GitHub App, the installation token is revoked once the backPorter is done.
+// Explicit requests run in a local checkout, e.g. the one of a developer, which is switched back to the branch
+// it was on once the backPorter is done. Returns an error if the setup or the backport process fails.
+func runRequest(ctx context.Context, cfg *Input, ghCtx *githubactions.GitHubContext, req *Request) error {
+	// The explicitly configured URLs take precedence over the ones of the GitHub instance the workflow runs on.
+	if cfg.GitHubAPIURL != "" {
+		ghCtx.APIURL = cfg.GitHubAPIURL
+	}
+	if cfg.GitHubServerURL != "" {
+		ghCtx.ServerURL = cfg.GitHubServerURL
+	}
+
+	b := &backPorter{git: git.NewGit(ghCtx), config: cfg, request: req}
+	if cfg.DryRun {
+		b.plan = &dryRunPlan{}
+	}
+	if req != nil {
+		head, err := b.git.Head(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to determine the checked out branch: %w", err)
+		}
+		defer func() {
+			if err := b.git.SwitchBack(context.WithoutCancel(ctx), head); err != nil {
+				githubactions.Warningf("Failed to switch back to %s: %v", head, err)
+			}
+		}()
+	}
+
+	if cfg.AppID != 0 {
+		app, err := github.NewAppAuth(ghCtx, cfg.AppID, cfg.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("failed to authenticate as GitHub App: %w", err)
+		}
+		// The installation token expires after an hour anyway, but there's no reason to keep it valid that long.
+		defer func() {
+			if err := app.Revoke(context.WithoutCancel(ctx)); err != nil {
+				githubactions.Warningf("Failed to revoke installation token: %v", err)
+			}
+		}()
+
+		client, err := github.NewAppClient(ghCtx, app)
+		if err != nil {
+			return fmt.Errorf("failed to create GitHub client: %w", err)
+		}
+		b.github, b.git = client, b.git.WithAuthToken(app.Token)
+	} else {
+		client, err := github.NewClient(ghCtx, cfg.GitHubToken)
+		if err != nil {
+			return fmt.Errorf("failed to create GitHub client: %w", err)
+		}
+		b.github = client
+	}
+	return b.Run(ctx)
+}
+
 // Run performs the backporting of the pull request to the target branches.
 //
 // This method orchestrates the entire backporting process, including determining target branches,
 // cherry-picking commits, handling conflicts, creating backport branches and pull requests, and
 // commenting on the original pull request with the results.
 func (b *backPorter) Run(ctx context.Context) error {
-	srcPrNumber, err := b.github.GetPrNumber()
-	if err != nil {
+	var err error
+	isCommand := b.request == nil && b.github.EventName() == github.EventIssueComment
+	var commandRefs []string // The branches requested via a slash command, if triggered by a comment.
+	if isCommand {
+		if action := b.github.EventAction(); action != "created" {
+			githubactions.Infof("Ignoring '%s' comment event, only new comments can trigger backports.", action)
+			return nil
+		}
+		body, _, err := b.github.GetComment()
+		if err != nil {
+			return err
+		}
+		branches, ok := parseCommand(body)
+		if !ok {
+			githubactions.Infof("Comment does not contain a %s command, skipping.", commandName)
+			return nil
+		}
+		commandRefs = branches
+	}
+
+	var srcPrNumber int64
+	if b.request != nil {
+		srcPrNumber = b.request.PrNumber
+	} else if srcPrNumber, err = b.github.GetPrNumber(); err != nil {
 		return err
 	}
 
@@ -53,68 +154,82 @@ func (b *backPorter) Run(ctx context.Context) error {
 		return err
 	}
 
+	b.report = &report{sourcePr: sourcePr}
+	defer func() {
+		outputs, err := b.report.outputs()
+		if err != nil {
+			githubactions.Errorf("Failed to set outputs: %v", err)
+		}
+		for _, name := range slices.Sorted(maps.Keys(outputs)) {
+			githubactions.SetOutput(name, outputs[name])
+		}
+
+		summary := b.report.String()
+		if b.plan != nil {
+			githubactions.Infof("Dry run finished, nothing was pushed or posted. Plan:\n%s", b.plan)
+			summary += b.plan.String()
+		}
+		githubactions.AddStepSummary(summary)
+	}()
+
 	githubactions.Group("Starting backport process")
 	defer githubactions.EndGroup()
 
+	// A label added to a pull request after it has been merged only triggers a backport to that very label,
+	// all the other target branches have already been taken care of when the pull request was closed.
+	isLabeled := b.request == nil && !isCommand && b.github.EventAction() == "labeled"
 	if !sourcePr.GetMerged() {
+		if isLabeled {
+			githubactions.Infof("Pull request #%d is not merged yet, the label will be processed once it's merged.", srcPrNumber)
+			return nil
+		}
 		githubactions.Warningf("Pull request #%d is not merged, skipping backport.", srcPrNumber)
 		// See https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows#pull_request_target.
-		return b.github.CreateComment(ctx, srcPrNumber, "⚠️ For security reasons, backbot backports merged pull requests only. Aborting.")
+		return b.comment(ctx, srcPrNumber, commentFailure, nil, "⚠️ For security reasons, backbot backports merged pull requests only. Aborting.")
 	}
 
-	targetRefs := b.getTargetRefs(ctx, sourcePr)
-	if len(targetRefs) == 0 {
-		githubactions.Infof("No target branches found for backporting. Exiting.")
-		return nil
-	}
-
-	githubactions.Infof("Fetching commits for pull request #%d with %d commits", srcPrNumber, sourcePr.GetCommits())
-	// Fetch the commits of the source PR to ensure we have them locally.
-	if err := b.git.Fetch(ctx, sourcePr.GetHead().GetSHA(), sourcePr.GetCommits()); err != nil {
-		return fmt.Errorf("failed to fetch commits for PR #%d: %w", srcPrNumber, err)
-	}
-
-	mk, err := b.github.MergeKind(ctx, sourcePr)
-	if err != nil {
+	if err := b.loadRepoConfig(ctx); err != nil {
 		return err
 	}
+	if b.config.Rerere {
+		// Only backport PRs that have just been merged are recorded, not the ones triggering a backport of their own.
+		record := b.request == nil && !isCommand && !isLabeled
+		if err := b.setUpRerere(ctx, sourcePr, record); err != nil {
+			return err
+		}
+	}
 
-	// Depending on the merge strategy used, the merge commit from the PR[^1] represents 3 different things:
-	// 1. Merge commit strategy: the merge commit is a real merge commit with 2 parents, and the commits
-	//    in the PR are the commits to cherry-pick (excluding the merge commit).
-	// 2. Squash and merge strategy: the merge commit is a single commit that squashes all commits in the PR,
-	//    and is the only commit to cherry-pick (the commits in the PR are not relevant).
-	// 3. Rebase and merge strategy: the merge commit is a single commit that represents the last commit in the PR,
-	//    but with a different SHA [^2]. The commits in the PR have different SHAs than those in the target branch,
-	//    so we need to find the new SHAs of the commits to cherry-pick by looking backward starting from the merge
-	//    commit plus the number of commits in the PR.
-	//
-	// [^1]: https://docs.github.com/en/rest/pulls/pulls?apiVersion=2022-11-28#get-a-pull-request
-	// [^2]: https://docs.github.com/en/repositories/configuring-branches-and-merges-in-your-repository/configuring-pull-request-merges/about-merge-methods-on-github#rebasing-and-merging-your-commits
-	var commitSHAs []string // The SHAs of the commits to cherry-pick
-	switch mk {
-	case github.Squash:
-		githubactions.Infof("Pull request was merged with a squash commit, cherry-picking the squash commit %s", sourcePr.GetMergeCommitSHA())
-		commitSHAs = []string{sourcePr.GetMergeCommitSHA()}
-	case github.MergeCommit:
-		githubactions.Infof("Pull request was merged with a merge commit, cherry-picking all commits from #%d excluding the merge commit", srcPrNumber)
-		commits, err := b.github.GetCommits(ctx, sourcePr)
+	var targets []target
+	switch {
+	case b.request != nil:
+		githubactions.Infof("Backport to branch(es) %v requested explicitly", b.request.Branches)
+		targets = targetsOf(b.fetchTargetRefs(ctx, b.request.Branches))
+	case isCommand:
+		refs, err := b.getCommandTargetRefs(ctx, srcPrNumber, commandRefs)
 		if err != nil {
 			return err
 		}
-		for _, commit := range commits {
-			commitSHAs = append(commitSHAs, commit.GetSHA())
-		}
-	case github.Rebase:
-		githubactions.Infof("Pull request was merged with rebase, finding commits to cherry-pick")
-		ranges, err := b.git.FindCommitRange(ctx, fmt.Sprintf("%s~%d..%s", sourcePr.GetMergeCommitSHA(), sourcePr.GetCommits(), sourcePr.GetMergeCommitSHA()))
+		targets = targetsOf(refs)
+	case isLabeled:
+		label, err := b.github.GetEventLabel()
 		if err != nil {
 			return err
 		}
-		commitSHAs = ranges
+		githubactions.Infof("Pull request #%d was labeled with '%s' after being merged", srcPrNumber, label)
+		targets = b.getTargetRefs(ctx, []*v75github.Label{{Name: v75github.Ptr(label)}})
 	default:
-		githubactions.Fatalf("Could not determine merge strategy '%s' for pull request #%d, skipping backport.", mk, srcPrNumber)
+		targets = b.getTargetRefs(ctx, sourcePr.Labels)
+	}
+	if len(targets) == 0 {
+		githubactions.Infof("No target branches found for backporting. Exiting.")
+		return nil
+	}
+
+	mk, commitSHAs, err := b.sourceCommits(ctx, sourcePr)
+	if err != nil {
+		return err
 	}
+	b.report.mergeKind = mk
 
 	if mk != github.Squash && len(commitSHAs) != 0 { // Squash PR cannot have merge commits
 		mergeCommitSHAs, err := b.git.FindCommitRange(ctx, "--merges", fmt.Sprintf(
@@ -133,7 +248,10 @@ func (b *backPorter) Run(ctx context.Context) error {
 					"Found merge commit(s) %v in pull request #%d, aborting backport as per configuration",
 					mergeCommitSHAs, srcPrNumber,
 				)
-				return b.github.CreateComment(ctx, srcPrNumber, fmt.Sprintf(
+				for _, t := range targets {
+					b.report.add(t.ref, outcomeAborted, commitSHAs, "the pull request contains merge commits")
+				}
+				return b.comment(ctx, srcPrNumber, commentFailure, nil, fmt.Sprintf(
 					"⚠️ Found merge commit(s) %v in pull request #%d, backport aborted as per configuration.",
 					mergeCommitSHAs, srcPrNumber,
 				))
@@ -151,39 +269,124 @@ func (b *backPorter) Run(ctx context.Context) error {
 
 	if len(commitSHAs) == 0 {
 		githubactions.Infof("No commits to cherry-pick after applying configuration, exiting.")
-		return b.github.CreateComment(ctx, srcPrNumber, "⚠️ No commits to cherry-pick after applying configuration, skipping backport.")
-	}
-
-	labelsToAdd, err := b.getLabelsToAdd(sourcePr)
-	if err != nil {
-		return err
+		for _, t := range targets {
+			b.report.add(t.ref, outcomeSkipped, nil, "no commits to cherry-pick after applying the configuration")
+		}
+		return b.comment(ctx, srcPrNumber, commentInfo, nil, "⚠️ No commits to cherry-pick after applying configuration, skipping backport.")
 	}
 
 	var prList string
-	var refList []string
-	for _, targetRef := range targetRefs {
-		backportRef := makeBackportBranchName(srcPrNumber, targetRef)
+	var refList, backportedRefs, existingRefs []string
+	for _, t := range targets {
+		targetRef, cfg := t.ref, b.config.forTarget(t)
+		backportRef := makeBackportBranchName(b.config.BranchNameTemplate, targetRef, sourcePr)
+		if err := validateBranchName(backportRef); err != nil {
+			githubactions.Errorf("Cannot backport to branch %s: %v", targetRef, err)
+			b.report.add(targetRef, outcomeFailed, commitSHAs, fmt.Sprintf("the backport branch name `%s` is invalid", backportRef))
+			continue
+		}
+
+		targetCommitSHAs, err := b.unappliedCommits(ctx, targetRef, commitSHAs)
+		if err != nil {
+			githubactions.Warningf("Failed to find commits already applied to branch %s, cherry-picking all: %v", targetRef, err)
+			targetCommitSHAs = commitSHAs
+		}
+		if len(targetCommitSHAs) == 0 {
+			githubactions.Infof("All commits have already been backported to branch %s, skipping it.", targetRef)
+			b.report.add(targetRef, outcomeBackported, commitSHAs, "all commits are already present on the branch")
+			backportedRefs = append(backportedRefs, fmt.Sprintf("`%s`", targetRef))
+			continue
+		}
+
+		existing, err := b.findExistingBackport(ctx, targetRef, backportRef)
+		if err != nil {
+			githubactions.Errorf("Failed to look up existing backport branch %s: %v", backportRef, err)
+			b.report.add(targetRef, outcomeFailed, targetCommitSHAs, fmt.Sprintf("failed to look up an existing `%s`", backportRef))
+			continue
+		}
+		startPoint := fmt.Sprintf("origin/%s", targetRef)
+		if existing != nil {
+			var result *backportResult
+			startPoint, targetCommitSHAs, result = b.prepareExistingBackport(ctx, cfg, existing, targetRef, backportRef, targetCommitSHAs)
+			if result != nil {
+				if result.outcome == outcomeSkipped {
+					existingRefs = append(existingRefs, existing.describe(targetRef))
+				}
+				continue
+			}
+		}
+
+		b.plan.addBackport(targetRef, backportRef)
 		githubactions.Infof("Creating backport branch %s for target branch %s", backportRef, targetRef)
 
-		// Checkout the new backport branch locally starting from the target branch.
-		if err := b.git.Checkout(ctx, backportRef, fmt.Sprintf("origin/%s", targetRef)); err != nil {
+		// Checkout the backport branch locally starting from the target branch or the existing backport branch.
+		// A local branch of the same name is only reset if the existing backport is recreated anyway.
+		if err := b.git.Checkout(ctx, backportRef, startPoint, existing.forcePush()); err != nil {
 			githubactions.Errorf("Failed to checkout backport branch %s: %v", backportRef, err)
+			b.report.add(targetRef, outcomeFailed, targetCommitSHAs, fmt.Sprintf("failed to check out `%s`", backportRef))
 			continue
 		}
 
-		if newPr := b.cherryPick(ctx, sourcePr, targetRef, backportRef, commitSHAs); newPr != nil {
-			if _, err := b.github.LabelPR(ctx, sourcePr, labelsToAdd...); err != nil {
+		result := b.cherryPick(ctx, cfg, sourcePr, targetRef, backportRef, targetCommitSHAs, existing)
+		b.report.results = append(b.report.results, result)
+		if newPr := result.pr; newPr != nil {
+			if err := b.labelPR(ctx, newPr, getLabelsToAdd(cfg, sourcePr)...); err != nil {
 				githubactions.Errorf("Failed to add labels to backport PR for branch %s: %v", backportRef, err)
 			}
+			if err := b.requestReviewers(ctx, newPr, b.reviewersOf(ctx, cfg, sourcePr, newPr)...); err != nil {
+				githubactions.Errorf("Failed to request reviewers for backport PR #%d: %v", newPr.GetNumber(), err)
+			}
+			if err := b.assignPR(ctx, newPr, assigneesOf(cfg, sourcePr)...); err != nil {
+				githubactions.Errorf("Failed to assign users to backport PR #%d: %v", newPr.GetNumber(), err)
+			}
+			if milestone := b.milestoneOf(ctx, cfg, sourcePr, result); milestone != nil {
+				if err := b.setMilestone(ctx, newPr, milestone); err != nil {
+					githubactions.Errorf("Failed to set milestone of backport PR #%d: %v", newPr.GetNumber(), err)
+				}
+			}
+			for _, project := range b.projectsOf(ctx, cfg, sourcePr) {
+				if err := b.addToProject(ctx, newPr, project); err != nil {
+					githubactions.Errorf("Failed to add backport PR #%d to project %q: %v", newPr.GetNumber(), project.Title, err)
+				}
+			}
+
+			var autoMerge string
+			if cfg.AutoMerge && result.outcome != outcomeDraft && !newPr.GetDraft() {
+				if len(result.resolved) != 0 {
+					githubactions.Infof("Not enabling auto-merge on backport PR #%d, its conflicts were resolved automatically", newPr.GetNumber())
+				} else if err := b.enableAutoMerge(ctx, newPr, cfg.AutoMergeMethod); err != nil {
+					githubactions.Errorf("Failed to enable auto-merge on backport PR #%d: %v", newPr.GetNumber(), err)
+					autoMerge = " (enabling auto-merge failed)"
+				} else {
+					result.autoMerge = true
+					autoMerge = fmt.Sprintf(" (auto-merge enabled, %s)", cfg.AutoMergeMethod)
+				}
+			}
 
 			refList = append(refList, fmt.Sprintf("`%s`", targetRef))
-			prList += fmt.Sprintf("- #%d\n", newPr.GetNumber())
+			prList += fmt.Sprintf("- #%d%s\n", newPr.GetNumber(), autoMerge)
 		}
 	}
 
+	if len(refList) == 0 && len(backportedRefs)+len(existingRefs) == len(targets) {
+		var notes []string
+		if len(backportedRefs) != 0 {
+			notes = append(notes, fmt.Sprintf(
+				"ℹ️ The changes have already been backported to the following branch(es), nothing to do: %s",
+				strings.Join(backportedRefs, ", "),
+			))
+		}
+		if len(existingRefs) != 0 {
+			notes = append(notes, fmt.Sprintf(
+				"ℹ️ Backports to the following branch(es) already exist and were left as is: %s",
+				strings.Join(existingRefs, ", "),
+			))
+		}
+		return b.comment(ctx, srcPrNumber, commentInfo, nil, strings.Join(notes, "\n\n"))
+	}
 	if len(refList) == 0 {
 		githubactions.Infof("No backport PRs were created successfully, exiting.")
-		return b.github.CreateComment(ctx, srcPrNumber, "⚠️ No backport PRs were created successfully. See the GitHub Actions logs for details.")
+		return b.comment(ctx, srcPrNumber, commentFailure, nil, "⚠️ No backport PRs were created successfully. "+seeLogs(b.github.WorkflowRunURL()))
 	}
 
 	// Finally, comment on the source PR with the results of the backport operation.
@@ -191,150 +394,333 @@ func (b *backPorter) Run(ctx context.Context) error {
 		"Successfully created backport PR(s) onto the following branch(es): %s\n\n---\n%s",
 		strings.Join(refList, ", "), prList,
 	)
-	return b.github.CreateComment(ctx, srcPrNumber, successBody)
+	if len(backportedRefs) != 0 {
+		successBody += fmt.Sprintf(
+			"\nThe changes have already been backported to the following branch(es): %s\n", strings.Join(backportedRefs, ", "),
+		)
+	}
+	if len(existingRefs) != 0 {
+		successBody += fmt.Sprintf(
+			"\nBackports to the following branch(es) already exist and were left as is: %s\n", strings.Join(existingRefs, ", "),
+		)
+	}
+	return b.comment(ctx, srcPrNumber, commentSuccess, nil, successBody)
+}
+
+// sourceCommits fetches the commits of the given merged source PR and determines the ones to cherry-pick.
+//
+// Returns the merge kind of the source PR and the SHAs of its commits in chronological order, including any
+// merge commits, or an error if the operation fails.
+func (b *backPorter) sourceCommits(ctx context.Context, sourcePr *v75github.PullRequest) (github.MergeKind, []string, error) {
+	srcPrNumber := sourcePr.GetNumber()
+	githubactions.Infof("Fetching commits for pull request #%d with %d commits", srcPrNumber, sourcePr.GetCommits())
+	// Fetch the commits of the source PR to ensure we have them locally.
+	if err := b.git.Fetch(ctx, sourcePr.GetHead().GetSHA(), sourcePr.GetCommits()); err != nil {
+		return github.MergeInvalid, nil, fmt.Errorf("failed to fetch commits for PR #%d: %w", srcPrNumber, err)
+	}
+
+	mk, err := b.github.MergeKind(ctx, sourcePr)
+	if err != nil {
+		return github.MergeInvalid, nil, err
+	}
+
+	// Depending on the merge strategy used, the merge commit from the PR[^1] represents 3 different things:
+	// 1. Merge commit strategy: the merge commit is a real merge commit with 2 parents, and the commits
+	//    in the PR are the commits to cherry-pick (excluding the merge commit).
+	// 2. Squash and merge strategy: the merge commit is a single commit that squashes all commits in the PR,
+	//    and is the only commit to cherry-pick (the commits in the PR are not relevant).
+	// 3. Rebase and merge strategy: the merge commit is a single commit that represents the last commit in the PR,
+	//    but with a different SHA [^2]. The commits in the PR have different SHAs than those in the target branch,
+	//    so we need to find the new SHAs of the commits to cherry-pick by looking backward starting from the merge
+	//    commit plus the number of commits in the PR.
+	//
+	// [^1]: https://docs.github.com/en/rest/pulls/pulls?apiVersion=2022-11-28#get-a-pull-request
+	// [^2]: https://docs.github.com/en/repositories/configuring-branches-and-merges-in-your-repository/configuring-pull-request-merges/about-merge-methods-on-github#rebasing-and-merging-your-commits
+	var commitSHAs []string // The SHAs of the commits to cherry-pick
+	switch mk {
+	case github.Squash:
+		githubactions.Infof("Pull request was merged with a squash commit, cherry-picking the squash commit %s", sourcePr.GetMergeCommitSHA())
+		commitSHAs = []string{sourcePr.GetMergeCommitSHA()}
+	case github.MergeCommit:
+		githubactions.Infof("Pull request was merged with a merge commit, cherry-picking all commits from #%d excluding the merge commit", srcPrNumber)
+		commits, err := b.github.GetCommits(ctx, sourcePr)
+		if err != nil {
+			return github.MergeInvalid, nil, err
+		}
+		for _, commit := range commits {
+			commitSHAs = append(commitSHAs, commit.GetSHA())
+		}
+	case github.Rebase:
+		githubactions.Infof("Pull request was merged with rebase, finding commits to cherry-pick")
+		ranges, err := b.git.FindCommitRange(ctx, fmt.Sprintf("%s~%d..%s", sourcePr.GetMergeCommitSHA(), sourcePr.GetCommits(), sourcePr.GetMergeCommitSHA()))
+		if err != nil {
+			return github.MergeInvalid, nil, err
+		}
+		commitSHAs = ranges
+	default:
+		return github.MergeInvalid, nil, fmt.Errorf("could not determine merge strategy '%s' for pull request #%d", mk, srcPrNumber)
+	}
+	return mk, commitSHAs, nil
 }
 
 // cherryPick attempts to cherry-pick the specified commits onto the backport branch.
 //
-// It handles conflicts based on the configuration, either aborting the backport or creating
-// a draft PR for manual resolution. If successful, it pushes the backport branch and creates
-// a pull request and returns it, otherwise returns nil.
+// It handles conflicts based on the configuration, either aborting the backport, creating a draft PR
+// for manual resolution, with or without the conflict markers, or retrying with merge strategy options
+// first. If successful, it pushes the backport branch and creates a pull request. It returns the result
+// of the backport, including the created PR, if any.
 //
+// If the backport branch exists from a previous run, it's pushed as described by the given existing backport,
+// and its open PR is reused, if any. The existing backport is nil otherwise.
+//
+// The given inputs are the ones that apply to the target branch, see [Input.ForBranch].
 // All encountered errors are sent to GitHub Actions logs.
-func (b *backPorter) cherryPick(ctx context.Context, srcPr *v75github.PullRequest, targetRef, backportRef string, commitSHAs []string) *v75github.PullRequest {
-	srcPrNum := int64(srcPr.GetNumber())
+func (b *backPorter) cherryPick(ctx context.Context, cfg *Input, srcPr *v75github.PullRequest, targetRef, backportRef string, commitSHAs []string, existing *existingBackport) *backportResult {
+	result := &backportResult{target: targetRef, branch: backportRef, commitSHAs: commitSHAs}
 
-	switch b.config.ConflictHandling {
+	switch cfg.ConflictHandling {
 	case ConflictHandlingAbort:
 		if err := b.git.CherryPick(ctx, false, commitSHAs...); err != nil {
-			githubactions.Errorf("Failed to cherry pick commits: %v", err)
-
-			msg := fmt.Sprintf(
-				"⚠️ Conflict occurred while backporting to branch %s. Aborting backport as per configuration.",
-				targetRef,
-			)
-			if err := b.github.CreateComment(ctx, srcPrNum, msg); err != nil {
-				githubactions.Errorf("Failed to create comment on PR #%d: %v", srcPrNum, err)
-			}
-			return nil
+			return b.abortBackport(ctx, srcPr, result, err)
 		}
-	case ConflictHandlingDraft:
+	case ConflictHandlingDraft, ConflictHandlingMarkers, ConflictHandlingStrategy:
 		for i, commitSHA := range commitSHAs {
-			if err := b.git.CherryPick(ctx, true, commitSHA); err != nil {
-				if git.IsConflictErr(err) {
-					githubactions.Warningf(
-						"Conflict occurred while cherry-picking commit %s to branch %s, trying to prepare for manual backport.",
-						commitSHA, targetRef,
-					)
-
-					// Push the backport branch with the draft commit to remote.
-					if err := b.git.Push(ctx, backportRef); err != nil {
-						githubactions.Errorf("Failed to push backport branch %s: %v", backportRef, err)
-						return nil
-					}
-					newPr, err := b.github.CreatePR(ctx, b.makeNewPullRequest(srcPr, targetRef, backportRef, true))
-					if err != nil {
-						githubactions.Errorf("Failed to create draft PR for backport branch %s: %v", backportRef, err)
-						return nil
+			var err error
+			handling := cfg.ConflictHandling
+			if handling == ConflictHandlingStrategy {
+				if err = b.git.CherryPick(ctx, false, commitSHA); err != nil && git.IsConflictErr(err) {
+					var option string
+					if option, err = b.retryWithStrategyOptions(ctx, cfg, commitSHA); err == nil {
+						result.resolved = append(result.resolved, resolvedConflict{commitSHA: commitSHA, option: option})
+					} else if git.IsConflictErr(err) {
+						handling = cfg.StrategyFallback
 					}
-					msg := fmt.Sprintf(
-						"⚠️ Backporting commit %s to branch `%s` causes a conflict. Created draft PR for manual resolution.\n\n",
-						commitSHA, targetRef,
-					)
-					msg += fmt.Sprintf("### Manual Backport Steps\n```bash\n%s\n```\n", listManualSteps(backportRef, commitSHAs[i:]))
-					if err := b.github.CreateComment(ctx, srcPrNum, msg); err != nil {
-						githubactions.Errorf("Failed to create comment on PR #%d: %v", srcPrNum, err)
-					}
-					if err := b.github.CreateComment(ctx, int64(newPr.GetNumber()), msg); err != nil {
-						githubactions.Errorf("Failed to create comment on draft PR #%d: %v", newPr.GetNumber(), err)
-					}
-					return newPr
 				}
+			}
 
+			conflict := false
+			switch handling {
+			case ConflictHandlingAbort: // Only reached as the fallback of the strategy conflict handling.
+				return b.abortBackport(ctx, srcPr, result, err)
+			case ConflictHandlingDraft, ConflictHandlingMarkers:
+				// With the strategy conflict handling, the commit is cherry-picked once more here to create the
+				// draft commit, and conflicts just like before.
+				conflict, err = b.cherryPickCommit(ctx, handling, commitSHA, result)
+			}
+			if err != nil {
 				githubactions.Errorf("Failed to create commit for cherry-pick of %s: %v", commitSHA, err)
-				return nil
+				result.outcome, result.details = outcomeFailed, fmt.Sprintf("failed to cherry-pick `%.7s`", commitSHA)
+				return result
+			}
+			if conflict {
+				return b.draftBackport(ctx, cfg, srcPr, backportRef, commitSHAs[i:], result, existing)
 			}
 			githubactions.Infof("Successfully cherry-picked commit %s to branch %s", commitSHA, targetRef)
 		}
 	default:
-		githubactions.Errorf("Unknown conflict handling strategy: %s", b.config.ConflictHandling)
-		return nil
+		githubactions.Errorf("Unknown conflict handling strategy: %s", cfg.ConflictHandling)
+		result.outcome, result.details = outcomeFailed, "unknown conflict handling strategy"
+		return result
 	}
 
-	if err := b.git.Push(ctx, backportRef); err != nil {
+	// We've finished processing all commits for this target branch, so push the branch and create the PR.
+	pr, err := makeNewPullRequest(cfg, srcPr, result, backportRef, false)
+	if err != nil {
+		githubactions.Errorf("Failed to prepare PR for backport branch %s: %v", backportRef, err)
+		result.outcome, result.details = outcomeFailed, "failed to render the pull request templates"
+		return result
+	}
+	if err := b.push(ctx, backportRef, existing.forcePush()); err != nil {
 		githubactions.Errorf("Failed to push backport branch %s: %v", backportRef, err)
-		return nil
+		result.outcome, result.details = outcomeFailed, fmt.Sprintf("failed to push `%s`", backportRef)
+		return result
 	}
-
-	// We've finished processing all commits for this target branch, so create the PR.
-	newPr, err := b.github.CreatePR(ctx, b.makeNewPullRequest(srcPr, targetRef, backportRef, false))
+	newPr, reused, err := b.openPR(ctx, existing, result, pr)
 	if err != nil {
 		githubactions.Errorf("Failed to create PR for backport branch %s: %v", backportRef, err)
-		return nil
+		result.outcome, result.details = outcomeFailed, "failed to create the pull request"
+		return result
+	}
+	result.outcome, result.pr = outcomeCreated, newPr
+	if reused {
+		result.outcome = outcomeUpdated
+	} else {
+		githubactions.Infof("Created backport PR #%d for branch %s", newPr.GetNumber(), targetRef)
+	}
+	if len(result.resolved) != 0 {
+		result.details = "conflicts resolved with " + describeResolved(result.resolved)
+	}
+	return result
+}
+
+// unappliedCommits returns the given commits that haven't been applied to the given target branch yet.
+//
+// Commits that have been backported before, e.g. by hand or by a previous backport of a PR that was reverted,
+// are dropped, so that they are not cherry-picked once more. To detect them, the targetHistoryDepth most recent
+// commits of the target branch are fetched first. If that fails, e.g. because it takes too long in a large
+// repository, no commits can be detected and all of them are returned. Returns an error if the operation fails.
+func (b *backPorter) unappliedCommits(ctx context.Context, targetRef string, commitSHAs []string) ([]string, error) {
+	if err := b.git.Fetch(ctx, fmt.Sprintf("+%[1]s:refs/remotes/origin/%[1]s", targetRef), targetHistoryDepth); err != nil {
+		githubactions.Warningf("Failed to fetch the history of branch %s, cannot detect commits already applied to it: %v", targetRef, err)
+		return commitSHAs, nil
+	}
+
+	applied, err := b.git.FindApplied(ctx, "origin/"+targetRef, targetHistoryDepth, commitSHAs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var unapplied []string
+	for _, commitSHA := range commitSHAs {
+		if sha, ok := applied[commitSHA]; ok {
+			githubactions.Infof("Skipping commit %s, which has already been backported to %s as %s", commitSHA, targetRef, sha)
+			continue
+		}
+		unapplied = append(unapplied, commitSHA)
+	}
+	return unapplied, nil
+}
+
+// target is a branch to backport to, along with the rule whose label requested the backport, if any.
+type target struct {
+	ref  string
+	rule *labelRule // Nil unless requested by a label matching one of the rules of the config file.
+}
+
+// targetsOf returns the given target branches as targets that weren't requested by any rule.
+func targetsOf(refs []string) []target {
+	var targets []target
+	for _, ref := range refs {
+		targets = append(targets, target{ref: ref})
 	}
-	githubactions.Infof("Created backport PR #%d for branch %s", newPr.GetNumber(), targetRef)
-	return newPr
+	return targets
 }
 
-// getTargetRefs determines the target branches for backporting based on the configuration and source PR.
+// getTargetRefs determines the target branches for backporting based on the configuration and the given labels.
 //
-// This will only return slice of target branch references that match the LabelPattern in the configuration
-// or an empty slice if no matching branches are found.
-func (b *backPorter) getTargetRefs(ctx context.Context, sourcePr *v75github.PullRequest) []string {
-	if b.config.labelRegex == nil || len(sourcePr.Labels) == 0 {
+// The labels are usually all the labels of the source PR, or just the one that was added to an already merged PR.
+// Labels requesting backports to multiple supported branches are expanded to these branches in version order, and
+// branch aliases of the config file are resolved to the branches they refer to. The label patterns of the rules of
+// the config file are matched in order before the LabelPattern, and the first matching rule is returned along with
+// the branch. This will only return the targets with branches that exist in the repository, or an empty slice if
+// no matching branches are found. Each branch is only returned once, for the first label requesting it.
+func (b *backPorter) getTargetRefs(ctx context.Context, labels []*v75github.Label) []target {
+	if b.config.labelRegex == nil || len(labels) == 0 {
 		return nil
 	}
 	githubactions.Infof("Finding target branches matching pattern: %s", b.config.LabelPattern)
 
-	owner, repo := b.github.Repo()
+	var targets []target
+	add := func(t target) bool {
+		if slices.ContainsFunc(targets, func(other target) bool { return other.ref == t.ref }) {
+			return false
+		}
+		if err := b.fetchTargetRef(ctx, t.ref); err != nil {
+			return false
+		}
+		targets = append(targets, t)
+		return true
+	}
+	for _, label := range labels {
+		if since, ok := b.config.supportedSince(label.GetName()); ok {
+			supported, err := b.supportedBranches(ctx, since)
+			if err != nil {
+				githubactions.Warningf("Failed to list the supported branches for label '%s': %v", label.GetName(), err)
+				continue
+			}
+			githubactions.Infof("Label '%s' requests backports to the supported branches %v", label.GetName(), supported)
+			for _, branch := range supported {
+				add(target{ref: branch})
+			}
+			continue
+		}
 
-	var branches []string
-	for _, label := range sourcePr.Labels {
-		matches := b.config.labelRegex.FindStringSubmatch(label.GetName())
+		var rule *labelRule
+		pattern, matches := b.config.LabelPattern, b.config.labelRegex.FindStringSubmatch(label.GetName())
+		for _, r := range b.config.rules {
+			if ruleMatches := r.labelRegex.FindStringSubmatch(label.GetName()); len(ruleMatches) != 0 {
+				pattern, rule, matches = r.LabelPattern, r, ruleMatches
+				break
+			}
+		}
 		if len(matches) == 0 {
 			githubactions.Infof("Label '%s' does not match pattern '%s'", label.GetName(), b.config.LabelPattern)
 			continue
 		}
 		if len(matches) < 2 {
-			githubactions.Warningf("Label '%s' matches pattern '%s' but has no capturing group", label.GetName(), b.config.LabelPattern)
+			githubactions.Warningf("Label '%s' matches pattern '%s' but has no capturing group", label.GetName(), pattern)
 			continue
 		}
 		branch := matches[1]
-		if err := b.git.Fetch(ctx, fmt.Sprintf("+%[1]s:refs/remotes/origin/%[1]s", branch), 1); err != nil {
-			githubactions.Warningf(
-				"Branch '%s' extracted from label '%s' does not exist in repository %s/%s. %v",
-				branch, label.GetName(), owner, repo, err,
-			)
+		if alias, ok := b.config.branchAliases[branch]; ok {
+			githubactions.Infof("Branch '%s' of label '%s' is an alias of branch '%s'", branch, label.GetName(), alias)
+			branch = alias
+		}
+		if add(target{ref: branch, rule: rule}) {
+			githubactions.Infof("Label '%s' matches pattern '%s', adding branch '%s'", label.GetName(), pattern, branch)
+		}
+	}
+	return targets
+}
+
+// fetchTargetRef fetches the given target branch from the remote to make it available locally.
+//
+// Only the tip of the branch is fetched, its history is fetched later on if needed, see [backPorter.unappliedCommits].
+// Returns an error if the branch does not exist in the repository or cannot be fetched, which is also logged and
+// added to the report. A branch is only reported as missing if the remote confirms that it doesn't exist.
+func (b *backPorter) fetchTargetRef(ctx context.Context, branch string) error {
+	err := b.git.Fetch(ctx, fmt.Sprintf("+%[1]s:refs/remotes/origin/%[1]s", branch), 1)
+	if err == nil {
+		return nil
+	}
+
+	owner, repo := b.github.Repo()
+	if exists, existsErr := b.git.RemoteBranchExists(ctx, branch); existsErr == nil && !exists {
+		githubactions.Warningf("Branch '%s' does not exist in repository %s/%s. %v", branch, owner, repo, err)
+		b.report.add(branch, outcomeSkipped, nil, "the branch does not exist")
+		return err
+	}
+	githubactions.Errorf("Failed to fetch branch '%s' of repository %s/%s: %v", branch, owner, repo, err)
+	b.report.add(branch, outcomeFailed, nil, "failed to fetch the branch")
+	return err
+}
+
+// fetchTargetRefs fetches all the given target branches from the remote.
+//
+// It returns the subset of the branches that exist in the repository and were fetched successfully.
+func (b *backPorter) fetchTargetRefs(ctx context.Context, branches []string) []string {
+	var refs []string
+	for _, branch := range branches {
+		if err := b.fetchTargetRef(ctx, branch); err != nil {
 			continue
 		}
-		branches = append(branches, branch)
-		githubactions.Infof("Label '%s' matches pattern '%s', adding branch '%s'", label.GetName(), b.config.LabelPattern, branch)
+		refs = append(refs, branch)
 	}
-	return branches
+	return refs
 }
 
-// getLabelsToAdd determines the labels to add to backport PRs based on the configuration and source PR.
+// getLabelsToAdd determines the labels to add to backport PRs based on the given inputs and source PR.
 //
 // This will only return labels that match the CopyLabelsPattern excluding any labels that were used to
-// determine target branches. It returns a slice of labels to add or an error if the operation fails.
-func (b *backPorter) getLabelsToAdd(sourcePr *v75github.PullRequest) ([]string, error) {
-	if b.config.copyLabelRegex == nil || len(sourcePr.Labels) == 0 {
-		return nil, nil
+// determine target branches. It returns a slice of labels to add.
+func getLabelsToAdd(cfg *Input, sourcePr *v75github.PullRequest) []string {
+	if cfg.copyLabelRegex == nil || len(sourcePr.Labels) == 0 {
+		return nil
 	}
-	githubactions.Infof("Finding labels to copy matching pattern: %s", b.config.CopyLabelsPattern)
+	githubactions.Infof("Finding labels to copy matching pattern: %s", cfg.CopyLabelsPattern)
 
 	var labels []string
 	for _, label := range sourcePr.Labels {
-		if b.config.labelRegex != nil && b.config.labelRegex.MatchString(label.GetName()) {
+		if cfg.requestsBackport(label.GetName()) {
 			githubactions.Infof("Skipping label '%s' as it was used to determine target branches", label.GetName())
 			continue
 		}
-		if !b.config.copyLabelRegex.MatchString(label.GetName()) {
-			githubactions.Infof("Label '%s' does not match pattern '%s'", label.GetName(), b.config.CopyLabelsPattern)
+		if !cfg.copyLabelRegex.MatchString(label.GetName()) {
+			githubactions.Infof("Label '%s' does not match pattern '%s'", label.GetName(), cfg.CopyLabelsPattern)
 			continue
 		}
-		githubactions.Infof("Label '%s' matches pattern '%s', adding to backport PR labels", label.GetName(), b.config.CopyLabelsPattern)
+		githubactions.Infof("Label '%s' matches pattern '%s', adding to backport PR labels", label.GetName(), cfg.CopyLabelsPattern)
 		labels = append(labels, label.GetName())
 	}
-	return labels, nil
+	return labels
 }
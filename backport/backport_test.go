@@ -0,0 +1,829 @@
+package backport
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/require"
+	"github.com/yhabteab/backbot/git"
+	"github.com/yhabteab/backbot/github"
+	"github.com/yhabteab/backbot/github/githubtest"
+)
+
+func TestBackPorter(t *testing.T) {
+	t.Run("Squash", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0", "area/core")
+
+		b := repo.backPorter(fake, func(in *Input) { in.CopyLabelsPattern = "^area/" })
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.Equal(t, "backport-1-to-support/1.0", prs[0].GetHead().GetRef())
+		require.Equal(t, "support/1.0", prs[0].GetBase().GetRef())
+		require.Equal(t, "[Backport support/1.0] Add feature", prs[0].GetTitle())
+		require.False(t, prs[0].GetDraft())
+		require.Equal(t, []string{"area/core"}, fake.Labels(int64(prs[0].GetNumber())))
+		require.Empty(t, fake.Labels(1), "labels must not be added to the source PR")
+
+		require.Equal(t, "feature\n", repo.show("backport-1-to-support/1.0", "feature.txt"))
+		comments := fake.Comments(1)
+		require.Len(t, comments, 1)
+		require.Contains(t, comments[0], "Successfully created backport PR(s)")
+
+		require.Equal(t, github.Squash, b.report.mergeKind)
+		require.Len(t, b.report.results, 1)
+		require.Equal(t, outcomeCreated, b.report.results[0].outcome)
+		require.Equal(t, []string{sha}, b.report.results[0].commitSHAs)
+		require.Same(t, prs[0], b.report.results[0].pr)
+	})
+
+	t.Run("ConflictAbort", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.commit("support/1.0", "README.md", "diverged\n", "Diverge support branch")
+		sha := repo.commit("main", "README.md", "changed\n", "Change README")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+
+		b := repo.backPorter(fake, nil)
+		require.NoError(t, b.Run(context.Background()))
+		require.Empty(t, fake.CreatedPRs())
+		require.False(t, repo.hasBranch("backport-1-to-support/1.0"))
+		require.Len(t, b.report.results, 1)
+		require.Equal(t, outcomeAborted, b.report.results[0].outcome)
+
+		comments := fake.Comments(1)
+		require.Len(t, comments, 2)
+		require.Contains(t, comments[0], "Conflict occurred while backporting to branch support/1.0")
+		require.Contains(t, comments[0], fmt.Sprintf("Cherry-picking commit %s conflicts in the following files:\n- `README.md` (content): 1 conflict\n", sha))
+		require.Contains(t, comments[1], "No backport PRs were created successfully")
+	})
+
+	t.Run("ConflictDraft", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.commit("support/1.0", "README.md", "diverged\n", "Diverge support branch")
+		sha := repo.commit("main", "README.md", "changed\n", "Change README")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+
+		b := repo.backPorter(fake, func(in *Input) { in.ConflictHandling = ConflictHandlingDraft })
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.True(t, prs[0].GetDraft())
+		require.True(t, repo.hasBranch("backport-1-to-support/1.0"))
+		require.Len(t, fake.Comments(int64(prs[0].GetNumber())), 1)
+		require.Contains(t, fake.Comments(1)[0], "Created draft PR for manual resolution")
+		require.Contains(t, fake.Comments(1)[0], "The following files conflict:\n- `README.md` (content): 1 conflict\n")
+		require.Len(t, b.report.results, 1)
+		require.Equal(t, outcomeDraft, b.report.results[0].outcome)
+		require.Same(t, prs[0], b.report.results[0].pr)
+
+		outputs, err := b.report.outputs()
+		require.NoError(t, err)
+		require.Equal(t, `["support/1.0"]`, outputs["conflicting_targets"])
+		require.Equal(t, `[]`, outputs["failed_targets"])
+		require.Contains(t, outputs["created_pull_requests"], `"draft":true`)
+	})
+
+	t.Run("ConflictMarkers", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.commit("support/1.0", "README.md", "diverged\n", "Diverge support branch")
+		sha := repo.commit("main", "README.md", "changed\n", "Change README")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+
+		adjust := func(in *Input) {
+			in.ConflictHandling, in.ExistingBackportHandling = ConflictHandlingMarkers, ExistingBackportHandlingAppend
+		}
+		b := repo.backPorter(fake, adjust)
+		require.NoError(t, os.WriteFile(filepath.Join(repo.workspace, "artifact.bin"), []byte("build output\n"), 0o600))
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.True(t, prs[0].GetDraft())
+		require.Contains(t, prs[0].GetBody(), "- `README.md` (content): 1 conflict\n")
+		require.Contains(t, repo.show("backport-1-to-support/1.0", "README.md"), "<<<<<<< ")
+		require.Equal(t, "README.md", repo.git(repo.origin, "diff", "--name-only", "support/1.0", "backport-1-to-support/1.0"),
+			"untracked files of the workspace must not be committed")
+		require.Contains(t, fake.Comments(1)[0], "can be resolved right in the pull request")
+		require.Len(t, b.report.results, 1)
+		require.Equal(t, outcomeDraft, b.report.results[0].outcome)
+		require.Equal(t, []git.ConflictedFile{{Path: "README.md", Type: git.ConflictContent, Hunks: 1}}, b.report.results[0].conflictedFiles)
+
+		// The commit committed with conflict markers hasn't been backported yet, so a rerun must not skip it.
+		b = repo.backPorter(fake, adjust)
+		require.NoError(t, b.Run(context.Background()))
+		require.Len(t, fake.CreatedPRs(), 1)
+		require.Len(t, b.report.results, 1)
+		require.Equal(t, outcomeDraft, b.report.results[0].outcome)
+		require.Equal(t, []string{sha}, b.report.results[0].pending)
+	})
+
+	t.Run("ConflictStrategy", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.commit("support/1.0", "README.md", "diverged\n", "Diverge support branch")
+		sha := repo.commit("main", "README.md", "changed\n", "Change README")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+
+		b := repo.backPorter(fake, func(in *Input) {
+			in.ConflictHandling, in.StrategyOptions = ConflictHandlingStrategy, []string{"patience", "theirs"}
+			in.AutoMerge = true
+		})
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.False(t, prs[0].GetDraft())
+		require.Empty(t, fake.AutoMergeMethod(int64(prs[0].GetNumber())), "auto-merge must not be enabled on resolved conflicts")
+		require.Contains(t, prs[0].GetBody(), fmt.Sprintf("- %s: `--strategy-option=theirs`", sha))
+		require.Equal(t, "changed\n", repo.show("backport-1-to-support/1.0", "README.md"))
+		require.Len(t, b.report.results, 1)
+		require.Equal(t, outcomeCreated, b.report.results[0].outcome)
+		require.Equal(t, []resolvedConflict{{commitSHA: sha, option: "theirs"}}, b.report.results[0].resolved)
+	})
+
+	t.Run("ConflictStrategyFallback", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.commit("support/1.0", "README.md", "diverged\n", "Diverge support branch")
+		sha := repo.commit("main", "README.md", "changed\n", "Change README")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+
+		b := repo.backPorter(fake, func(in *Input) {
+			in.ConflictHandling, in.StrategyOptions = ConflictHandlingStrategy, []string{"patience"}
+			in.StrategyFallback = ConflictHandlingDraft
+		})
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.True(t, prs[0].GetDraft())
+		require.Contains(t, fake.Comments(1)[0], "Created draft PR for manual resolution")
+		require.Len(t, b.report.results, 1)
+		require.Equal(t, outcomeDraft, b.report.results[0].outcome)
+	})
+
+	t.Run("Rerere", func(t *testing.T) {
+		repo := newTestRepo(t)
+		diverged := repo.commit("support/1.0", "README.md", "diverged\n", "Diverge support branch")
+		repo.git(repo.upstream, "push", "origin", diverged+":refs/heads/support/2.0")
+		sha := repo.commit("main", "README.md", "changed\n", "Change README")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0", "backport-to-support/2.0")
+		enableRerere := func(in *Input) {
+			in.ConflictHandling, in.Rerere, in.RerereRef = ConflictHandlingDraft, true, "refs/backbot/rerere"
+		}
+
+		// Without any recorded resolutions, the conflict must be resolved by hand first.
+		fake.Event, fake.Action = github.EventPullRequest, "labeled"
+		fake.Label = "backport-to-support/1.0"
+		require.NoError(t, repo.backPorter(fake, enableRerere).Run(context.Background()))
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.True(t, prs[0].GetDraft())
+		resolution := repo.commit(prs[0].GetHead().GetRef(), "README.md", "resolved\n", "Resolve conflict")
+
+		// Merging the resolved backport PR records its resolution.
+		fake.AddPR(&v75github.PullRequest{
+			Number:  v75github.Ptr(100),
+			Merged:  v75github.Ptr(true),
+			Commits: v75github.Ptr(2),
+			Head:    &v75github.PullRequestBranch{Ref: prs[0].GetHead().Ref, SHA: v75github.Ptr(resolution)},
+			Base:    &v75github.PullRequestBranch{Ref: v75github.Ptr("support/1.0")},
+		}, github.Squash)
+		fake.Action, fake.PrNumber = "closed", 100
+		require.NoError(t, repo.backPorter(fake, enableRerere).Run(context.Background()))
+		require.NotEmpty(t, repo.git(repo.upstream, "ls-remote", "origin", "refs/backbot/rerere"))
+		require.Equal(t, "main", repo.git(repo.workspace, "symbolic-ref", "--short", "HEAD"), "the workspace must be switched back")
+
+		// The recorded resolution resolves the same conflict on another branch.
+		fake.Action, fake.PrNumber, fake.Label = "labeled", 1, "backport-to-support/2.0"
+		require.NoError(t, repo.backPorter(fake, enableRerere).Run(context.Background()))
+		prs = fake.CreatedPRs()
+		require.Len(t, prs, 2)
+		require.False(t, prs[1].GetDraft())
+		require.Equal(t, "resolved\n", repo.show("backport-1-to-support/2.0", "README.md"))
+	})
+
+	t.Run("AlreadyBackported", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.git(repo.upstream, "push", "origin", "main:support/2.0")
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		repo.commit("support/1.0", "feature.txt", "feature\n", "Add feature by hand")
+		repo.commit("support/2.0", "feature.txt", "feature, adjusted\n", "Add feature\n\n(cherry picked from commit "+sha+")")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0", "backport-to-support/2.0")
+
+		b := repo.backPorter(fake, nil)
+		require.NoError(t, b.Run(context.Background()))
+		require.Empty(t, fake.CreatedPRs())
+		require.False(t, repo.hasBranch("backport-1-to-support/1.0"))
+		require.Len(t, b.report.results, 2)
+		for _, result := range b.report.results {
+			require.Equal(t, outcomeBackported, result.outcome)
+		}
+		require.Equal(t, []string{
+			"ℹ️ The changes have already been backported to the following branch(es), nothing to do: `support/1.0`, `support/2.0`",
+		}, fake.Comments(1))
+	})
+
+	t.Run("ExistingBackportSkip", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+		require.NoError(t, repo.backPorter(fake, nil).Run(context.Background()))
+		head := repo.git(repo.origin, "rev-parse", "backport-1-to-support/1.0")
+
+		b := repo.backPorter(fake, nil)
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.Equal(t, head, repo.git(repo.origin, "rev-parse", "backport-1-to-support/1.0"))
+		require.Len(t, b.report.results, 1)
+		require.Equal(t, outcomeSkipped, b.report.results[0].outcome)
+		require.Same(t, prs[0], b.report.results[0].pr)
+
+		comments := fake.Comments(1)
+		require.Len(t, comments, 2)
+		require.Equal(t, "ℹ️ Backports to the following branch(es) already exist and were left as is: `support/1.0` (#2)", comments[1])
+	})
+
+	t.Run("ExistingBackportForce", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+		require.NoError(t, repo.backPorter(fake, nil).Run(context.Background()))
+		repo.commit("support/1.0", "other.txt", "other\n", "Change support branch")
+
+		b := repo.backPorter(fake, func(in *Input) { in.ExistingBackportHandling = ExistingBackportHandlingForce })
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.Equal(t, []string{"Add feature"}, repo.log("support/1.0", "backport-1-to-support/1.0"))
+		require.Len(t, b.report.results, 1)
+		require.Equal(t, outcomeUpdated, b.report.results[0].outcome)
+		require.Same(t, prs[0], b.report.results[0].pr)
+		require.Len(t, fake.Comments(2), 1)
+		require.Contains(t, fake.Comments(2)[0], "The backport branch was recreated and force-pushed")
+	})
+
+	t.Run("LocalBranch", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+
+		b := repo.backPorter(fake, nil)
+		repo.git(repo.workspace, "branch", "backport-1-to-support/1.0", "origin/main")
+		require.NoError(t, b.Run(context.Background()))
+		require.Empty(t, fake.CreatedPRs())
+		require.Equal(t, outcomeFailed, b.report.results[0].outcome)
+		require.Equal(t, sha, repo.git(repo.workspace, "rev-parse", "backport-1-to-support/1.0"), "local branches must not be reset")
+	})
+
+	t.Run("ExistingBackportAppend", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.git(repo.upstream, "push", "origin", "origin/support/1.0:refs/heads/backport-1-to-support/1.0")
+		repo.commit("backport-1-to-support/1.0", "fixup.txt", "fixup\n", "Fix up by hand")
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+		adjust := func(in *Input) { in.ExistingBackportHandling = ExistingBackportHandlingAppend }
+
+		b := repo.backPorter(fake, adjust)
+		require.NoError(t, b.Run(context.Background()))
+		require.Len(t, fake.CreatedPRs(), 1)
+		require.Equal(t, []string{"Fix up by hand", "Add feature"}, repo.log("support/1.0", "backport-1-to-support/1.0"))
+		require.Equal(t, outcomeCreated, b.report.results[0].outcome)
+
+		b = repo.backPorter(fake, adjust)
+		require.NoError(t, b.Run(context.Background()))
+		require.Len(t, fake.CreatedPRs(), 1)
+		require.Equal(t, []string{"Fix up by hand", "Add feature"}, repo.log("support/1.0", "backport-1-to-support/1.0"))
+		require.Len(t, b.report.results, 1)
+		require.Equal(t, outcomeSkipped, b.report.results[0].outcome)
+		require.Equal(t, "the backport branch `backport-1-to-support/1.0` is up to date", b.report.results[0].details)
+	})
+
+	t.Run("ExistingBackportConflict", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.git(repo.upstream, "push", "origin", "origin/support/1.0:refs/heads/backport-1-to-support/1.0")
+		repo.commit("backport-1-to-support/1.0", "README.md", "diverged\n", "Change README by hand")
+		sha := repo.commit("main", "README.md", "changed\n", "Change README")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+		fake.AddPR(&v75github.PullRequest{
+			Number: v75github.Ptr(2),
+			State:  v75github.Ptr("open"),
+			Draft:  v75github.Ptr(false),
+			Head:   &v75github.PullRequestBranch{Ref: v75github.Ptr("backport-1-to-support/1.0")},
+			Base:   &v75github.PullRequestBranch{Ref: v75github.Ptr("support/1.0")},
+		}, github.Squash)
+
+		b := repo.backPorter(fake, func(in *Input) {
+			in.ExistingBackportHandling, in.ConflictHandling = ExistingBackportHandlingAppend, ConflictHandlingDraft
+		})
+		require.NoError(t, b.Run(context.Background()))
+
+		require.Empty(t, fake.CreatedPRs())
+		existing, err := fake.GetPR(context.Background(), 2)
+		require.NoError(t, err)
+		require.True(t, existing.GetDraft(), "the reused PR must be converted to a draft")
+		require.Len(t, b.report.results, 1)
+		require.Equal(t, outcomeDraft, b.report.results[0].outcome)
+		require.Same(t, existing, b.report.results[0].pr)
+		require.Contains(t, fake.Comments(2)[0], "New commits were appended to the backport branch")
+	})
+
+	t.Run("ExistingBackportReady", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.git(repo.upstream, "push", "origin", "origin/support/1.0:refs/heads/backport-1-to-support/1.0")
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+		fake.AddPR(&v75github.PullRequest{
+			Number: v75github.Ptr(2),
+			State:  v75github.Ptr("open"),
+			Title:  v75github.Ptr("Stale title"),
+			Body:   v75github.Ptr("Stale body"),
+			Draft:  v75github.Ptr(true),
+			Head:   &v75github.PullRequestBranch{Ref: v75github.Ptr("backport-1-to-support/1.0")},
+			Base:   &v75github.PullRequestBranch{Ref: v75github.Ptr("support/1.0")},
+		}, github.Squash)
+
+		b := repo.backPorter(fake, func(in *Input) {
+			in.ExistingBackportHandling, in.AutoMerge = ExistingBackportHandlingForce, true
+		})
+		require.NoError(t, b.Run(context.Background()))
+
+		require.Empty(t, fake.CreatedPRs())
+		existing, err := fake.GetPR(context.Background(), 2)
+		require.NoError(t, err)
+		require.False(t, existing.GetDraft(), "the reused draft must be marked ready for review")
+		require.Equal(t, "[Backport support/1.0] Add feature", existing.GetTitle())
+		require.Equal(t, "Backport of #1 to support/1.0.", existing.GetBody())
+		require.Equal(t, AutoMergeMethodMerge, fake.AutoMergeMethod(2))
+		require.Equal(t, outcomeUpdated, b.report.results[0].outcome)
+		require.True(t, b.report.results[0].autoMerge)
+	})
+
+	t.Run("BranchNameTemplate", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+
+		b := repo.backPorter(fake, func(in *Input) {
+			in.BranchNameTemplate = "backport/${target_branch}/${original_pr_number}-${original_pr_title_slug}"
+		})
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.Equal(t, "backport/support/1.0/1-add-feature", prs[0].GetHead().GetRef())
+		require.Equal(t, []string{"Add feature"}, repo.log("support/1.0", "backport/support/1.0/1-add-feature"))
+	})
+
+	t.Run("ReviewersAndAssignees", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+		sourcePr, err := fake.GetPR(context.Background(), 1)
+		require.NoError(t, err)
+		sourcePr.User = &v75github.User{Login: v75github.Ptr("alice")}
+		sourcePr.MergedBy = &v75github.User{Login: v75github.Ptr("bob")}
+		fake.Approvers[1] = []string{"carol", "alice"}
+
+		b := repo.backPorter(fake, func(in *Input) {
+			in.Reviewers, in.AssignAuthor = []string{"org/team", "Bob"}, true
+			in.ReviewFrom = []string{ReviewFromAuthor, ReviewFromApprovers, ReviewFromMerger}
+		})
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.Equal(t, []string{"org/team", "Bob", "alice", "carol"}, fake.Reviewers(int64(prs[0].GetNumber())))
+		require.Equal(t, []string{"alice"}, fake.Assignees(int64(prs[0].GetNumber())))
+		require.Empty(t, fake.Assignees(1), "assignees must not be added to the source PR")
+	})
+
+	t.Run("BotAuthor", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+		sourcePr, err := fake.GetPR(context.Background(), 1)
+		require.NoError(t, err)
+		sourcePr.User = &v75github.User{Login: v75github.Ptr("dependabot[bot]"), Type: v75github.Ptr("Bot")}
+		sourcePr.MergedBy = &v75github.User{Login: v75github.Ptr("bob"), Type: v75github.Ptr("User")}
+
+		b := repo.backPorter(fake, func(in *Input) {
+			in.Reviewers, in.AssignAuthor = []string{"org/team"}, true
+			in.ReviewFrom = []string{ReviewFromAuthor, ReviewFromMerger}
+		})
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.Equal(t, []string{"org/team", "bob"}, fake.Reviewers(int64(prs[0].GetNumber())))
+		require.Empty(t, fake.Assignees(int64(prs[0].GetNumber())))
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+		sourcePr, err := fake.GetPR(context.Background(), 1)
+		require.NoError(t, err)
+		sourcePr.User = &v75github.User{Login: v75github.Ptr("alice")}
+		sourcePr.Assignees = []*v75github.User{{Login: v75github.Ptr("dave")}, {Login: v75github.Ptr("Alice")}}
+		fake.Milestones = []*v75github.Milestone{{Number: v75github.Ptr(3), Title: v75github.Ptr("v1.0")}}
+		fake.Projects[1] = []github.Project{{ID: "PVT_1", Title: "Releases"}}
+
+		b := repo.backPorter(fake, func(in *Input) {
+			in.AssignAuthor, in.CopyAssignees, in.CopyProjects = true, true, true
+			in.MilestoneTemplate = "v${target_version}"
+		})
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		number := int64(prs[0].GetNumber())
+		require.Equal(t, []string{"alice", "dave"}, fake.Assignees(number))
+		require.Equal(t, "v1.0", prs[0].GetMilestone().GetTitle())
+		require.Equal(t, []github.Project{{ID: "PVT_1", Title: "Releases"}}, fake.Projects[number])
+	})
+
+	t.Run("MilestoneNotFound", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+
+		b := repo.backPorter(fake, func(in *Input) { in.MilestoneTemplate = "v${target_version}" })
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.Nil(t, prs[0].GetMilestone())
+	})
+
+	t.Run("AutoMerge", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.git(repo.upstream, "push", "origin", "main:support/2.0")
+		repo.commit("support/2.0", "README.md", "diverged\n", "Diverge support branch")
+		sha := repo.commit("main", "README.md", "changed\n", "Change README")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0", "backport-to-support/2.0")
+
+		b := repo.backPorter(fake, func(in *Input) {
+			in.ConflictHandling, in.AutoMerge, in.AutoMergeMethod = ConflictHandlingDraft, true, AutoMergeMethodSquash
+		})
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 2)
+		require.Equal(t, "support/1.0", prs[0].GetBase().GetRef())
+		require.Equal(t, AutoMergeMethodSquash, fake.AutoMergeMethod(int64(prs[0].GetNumber())))
+		require.True(t, prs[1].GetDraft())
+		require.Empty(t, fake.AutoMergeMethod(int64(prs[1].GetNumber())), "auto-merge must not be enabled on drafts")
+
+		comments := fake.Comments(1)
+		require.Contains(t, comments[len(comments)-1], fmt.Sprintf("- #%d (auto-merge enabled, squash)\n- #%d\n", prs[0].GetNumber(), prs[1].GetNumber()))
+
+		outputs, err := b.report.outputs()
+		require.NoError(t, err)
+		require.Contains(t, outputs["created_pull_requests"], `"base":"support/1.0","draft":false,"auto_merge":true`)
+		require.Contains(t, outputs["created_pull_requests"], `"base":"support/2.0","draft":true,"auto_merge":false`)
+	})
+
+	t.Run("SupportedBranches", func(t *testing.T) {
+		repo := newTestRepo(t)
+		for _, branch := range []string{"support/0.9", "support/1.10", "support/1.2", "support/2.0", "feature/1.5"} {
+			repo.git(repo.upstream, "push", "origin", "main:"+branch)
+		}
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/2.0", "backport-since-1.0", "area/core")
+
+		b := repo.backPorter(fake, func(in *Input) { in.CopyLabelsPattern = ".*" })
+		require.NoError(t, b.Run(context.Background()))
+
+		var bases []string
+		for _, pr := range fake.CreatedPRs() {
+			bases = append(bases, pr.GetBase().GetRef())
+			require.Equal(t, []string{"area/core"}, fake.Labels(int64(pr.GetNumber())))
+		}
+		require.Equal(t, []string{"support/2.0", "support/1.0", "support/1.2", "support/1.10"}, bases)
+
+		fake = repo.fakeMergedPR(1, sha, github.Squash, "backport-to-all-supported")
+		b = repo.backPorter(fake, func(in *Input) { in.BranchNameTemplate = "all/${target_branch}/${original_pr_number}" })
+		require.NoError(t, b.Run(context.Background()))
+
+		bases = nil
+		for _, pr := range fake.CreatedPRs() {
+			bases = append(bases, pr.GetBase().GetRef())
+		}
+		require.Equal(t, []string{"support/0.9", "support/1.0", "support/1.2", "support/1.10", "support/2.0"}, bases)
+	})
+
+	t.Run("BranchAliases", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.git(repo.upstream, "push", "origin", "main:support/2.0")
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-lts", "backport-to-stable", "backport-to-support/2.0")
+
+		b := repo.backPorter(fake, func(in *Input) {
+			in.LabelPattern = `^backport-to-(\S+)$`
+			in.ApplyRepoConfig(&RepoConfig{BranchAliases: map[string]string{"lts": "support/1.0", "stable": "support/2.0"}})
+		})
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 2, "the branch of an alias must be backported to only once")
+		require.Equal(t, "support/1.0", prs[0].GetBase().GetRef())
+		require.Equal(t, "support/2.0", prs[1].GetBase().GetRef())
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.commit("support/1.0", "README.md", "diverged\n", "Diverge support branch")
+		repo.git(repo.upstream, "push", "origin", "support/1.0:support/2.0")
+		sha := repo.commit("main", "README.md", "changed\n", "Change README")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0", "try-backport-to-support/2.0", "area/core")
+
+		b := repo.backPorter(fake, func(in *Input) {
+			in.ApplyRepoConfig(&RepoConfig{Rules: []Rule{{
+				LabelPattern: `^try-backport-to-(\S+)$`,
+				Policy: Policy{
+					ConflictHandling:  v75github.Ptr(ConflictHandlingDraft),
+					Title:             v75github.Ptr("[Try ${target_branch}] ${original_pr_title}"),
+					CopyLabelsPattern: v75github.Ptr(".*"),
+					Reviewers:         []string{"alice"},
+				},
+			}}})
+		})
+		require.NoError(t, b.Run(context.Background()))
+
+		require.Len(t, b.report.results, 2)
+		require.Equal(t, "support/1.0", b.report.results[0].target)
+		require.Equal(t, outcomeAborted, b.report.results[0].outcome)
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.Equal(t, "support/2.0", prs[0].GetBase().GetRef())
+		require.Equal(t, "[Try support/2.0] Change README", prs[0].GetTitle())
+		require.True(t, prs[0].GetDraft())
+		require.Equal(t, []string{"area/core"}, fake.Labels(int64(prs[0].GetNumber())), "labels of rules must not be copied")
+		require.Equal(t, []string{"alice"}, fake.Reviewers(int64(prs[0].GetNumber())))
+	})
+
+	t.Run("Templates", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+
+		b := repo.backPorter(fake, func(in *Input) {
+			in.Title = "[{{ .TargetBranch | upper }}] ${original_pr_title}"
+			in.Description = "Backports {{ range .Commits }}{{ short . }}{{ end }} of #${original_pr_number}."
+			in.CommentTemplate = `{{ if eq .Kind "success" }}{{ .Message }}` + "\n\nThanks!{{ end }}"
+		})
+		require.NoError(t, b.Run(context.Background()))
+
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1)
+		require.Equal(t, "[SUPPORT/1.0] Add feature", prs[0].GetTitle())
+		require.Equal(t, fmt.Sprintf("Backports %.7s of #1.", sha), prs[0].GetBody())
+
+		comments := fake.Comments(1)
+		require.Len(t, comments, 1)
+		require.Contains(t, comments[0], "Successfully created backport PR(s)")
+		require.True(t, strings.HasSuffix(comments[0], "\n\nThanks!"))
+
+		// All comments but the successful ones are suppressed by the template.
+		b = repo.backPorter(fake, func(in *Input) {
+			in.CommentTemplate = `{{ if eq .Kind "success" }}{{ .Message }}{{ end }}`
+		})
+		require.NoError(t, b.Run(context.Background()))
+		require.Equal(t, outcomeSkipped, b.report.results[0].outcome)
+		require.Len(t, fake.Comments(1), 1)
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+
+		b := repo.backPorter(fake, nil)
+		b.plan = &dryRunPlan{}
+		require.NoError(t, b.Run(context.Background()))
+
+		require.Empty(t, fake.CreatedPRs())
+		require.Empty(t, fake.Comments(1))
+		require.False(t, repo.hasBranch("backport-1-to-support/1.0"))
+		require.Len(t, b.plan.backports, 1)
+		require.True(t, b.plan.backports[0].pushed)
+		require.Equal(t, "[Backport support/1.0] Add feature", b.plan.backports[0].pr.GetTitle())
+
+		outputs, err := b.report.outputs()
+		require.NoError(t, err)
+		require.Equal(t, `[]`, outputs["created_pull_requests"], "PRs that weren't created must be left out")
+	})
+
+	t.Run("Labeled", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.git(repo.upstream, "push", "origin", "main:support/2.0")
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0", "backport-to-support/2.0")
+		fake.Event, fake.Action, fake.Label = github.EventPullRequest, "labeled", "backport-to-support/2.0"
+
+		b := repo.backPorter(fake, nil)
+		require.NoError(t, b.Run(context.Background()))
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1, "only the newly added label must be backported")
+		require.Equal(t, "support/2.0", prs[0].GetBase().GetRef())
+		require.False(t, repo.hasBranch("backport-1-to-support/1.0"))
+		require.Len(t, b.report.results, 1)
+		require.Equal(t, "support/2.0", b.report.results[0].target)
+	})
+
+	t.Run("NotMerged", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash, "backport-to-support/1.0")
+		pr, err := fake.GetPR(context.Background(), 1)
+		require.NoError(t, err)
+		pr.Merged = v75github.Ptr(false)
+
+		require.NoError(t, repo.backPorter(fake, nil).Run(context.Background()))
+		require.Empty(t, fake.CreatedPRs())
+		require.Equal(t, []string{"⚠️ For security reasons, backbot backports merged pull requests only. Aborting."}, fake.Comments(1))
+	})
+
+	t.Run("UnknownMergeKind", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.MergeInvalid, "backport-to-support/1.0")
+
+		err := repo.backPorter(fake, nil).Run(context.Background())
+		require.ErrorContains(t, err, "could not determine merge strategy 'unknown' for pull request #1")
+		require.Empty(t, fake.CreatedPRs())
+	})
+
+	t.Run("Command", func(t *testing.T) {
+		repo := newTestRepo(t)
+		sha := repo.commit("main", "feature.txt", "feature\n", "Add feature")
+		fake := repo.fakeMergedPR(1, sha, github.Squash)
+		fake.Event, fake.Action = github.EventIssueComment, "created"
+		fake.CommentBody, fake.CommentAuthor = "/backport support/1.0 support/9.9", "octocat"
+
+		require.NoError(t, repo.backPorter(fake, nil).Run(context.Background()))
+		require.Empty(t, fake.CreatedPRs(), "users without write access must not trigger backports")
+		require.Contains(t, fake.Comments(1)[0], "only users with write access")
+
+		fake.Writers = []string{"octocat"}
+		b := repo.backPorter(fake, nil)
+		require.NoError(t, b.Run(context.Background()))
+		prs := fake.CreatedPRs()
+		require.Len(t, prs, 1, "non-existent branches must be skipped")
+		require.Equal(t, "support/1.0", prs[0].GetBase().GetRef())
+
+		require.Len(t, b.report.results, 2)
+		require.Equal(t, "support/9.9", b.report.results[0].target)
+		require.Equal(t, outcomeSkipped, b.report.results[0].outcome)
+		require.Equal(t, outcomeCreated, b.report.results[1].outcome)
+	})
+}
+
+// testRepo is a local git setup consisting of a bare origin repository with a main and support/1.0 branch,
+// an upstream clone used to push changes to the origin, and a workspace clone backbot operates on.
+type testRepo struct {
+	t         *testing.T
+	origin    string
+	upstream  string
+	workspace string
+}
+
+// newTestRepo creates a new testRepo with an isolated git configuration.
+//
+// The workspace is cloned lazily once backbot is about to run, so that it reflects all changes made by then.
+func newTestRepo(t *testing.T) *testRepo {
+	// Backbot relies on `git cherry-pick --empty=drop`, which was introduced in git 2.45.
+	output, err := exec.Command("git", "version").Output()
+	require.NoError(t, err)
+	var major, minor int
+	if _, err := fmt.Sscanf(string(output), "git version %d.%d", &major, &minor); err != nil || major < 2 || (major == 2 && minor < 45) {
+		t.Skipf("git >= 2.45 is required, got: %s", output)
+	}
+
+	dir := t.TempDir()
+	gitConfig := filepath.Join(dir, "gitconfig")
+	require.NoError(t, os.WriteFile(gitConfig, []byte(
+		"[user]\n\tname = Backbot\n\temail = backbot@example.com\n[init]\n\tdefaultBranch = main\n",
+	), 0o600))
+	t.Setenv("GIT_CONFIG_GLOBAL", gitConfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	r := &testRepo{
+		t:         t,
+		origin:    filepath.Join(dir, "origin.git"),
+		upstream:  filepath.Join(dir, "upstream"),
+		workspace: filepath.Join(dir, "workspace"),
+	}
+	r.git(dir, "init", "--bare", r.origin)
+	r.git(dir, "clone", r.origin, r.upstream)
+	r.commit("main", "README.md", "backbot\n", "Initial commit")
+	r.git(r.upstream, "push", "origin", "main:support/1.0")
+	return r
+}
+
+// git runs a git command in the given directory and returns its trimmed output.
+func (r *testRepo) git(dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoErrorf(r.t, err, "git %s: %s", strings.Join(args, " "), output)
+	return strings.TrimSpace(string(output))
+}
+
+// commit commits the given file content onto the branch and pushes it to the origin.
+//
+// Returns the SHA of the new commit.
+func (r *testRepo) commit(branch, file, content, message string) string {
+	if r.git(r.upstream, "ls-remote", "--heads", "origin", branch) != "" {
+		r.git(r.upstream, "fetch", "origin", branch)
+		r.git(r.upstream, "switch", "--force-create", branch, "FETCH_HEAD")
+	}
+	require.NoError(r.t, os.WriteFile(filepath.Join(r.upstream, file), []byte(content), 0o600))
+	r.git(r.upstream, "add", file)
+	r.git(r.upstream, "commit", "--message", message)
+	r.git(r.upstream, "push", "origin", branch)
+	return r.git(r.upstream, "rev-parse", "HEAD")
+}
+
+// show returns the content of the given file on the given branch of the origin.
+func (r *testRepo) show(branch, file string) string {
+	return r.git(r.origin, "show", branch+":"+file) + "\n"
+}
+
+// hasBranch checks whether the given branch exists in the origin.
+func (r *testRepo) hasBranch(branch string) bool {
+	return r.git(r.origin, "branch", "--list", branch) != ""
+}
+
+// fakeMergedPR returns a Fake with a PR merged as the given commit, triggering a closed event.
+func (r *testRepo) fakeMergedPR(number int, mergeSHA string, mk github.MergeKind, labels ...string) *githubtest.Fake {
+	fake := githubtest.NewFake("icinga", "backbot")
+	fake.Event, fake.Action, fake.PrNumber = github.EventPullRequest, "closed", int64(number)
+
+	pr := &v75github.PullRequest{
+		Number:         v75github.Ptr(number),
+		Title:          v75github.Ptr(r.git(r.upstream, "log", "-1", "--format=%s", mergeSHA)),
+		Merged:         v75github.Ptr(true),
+		MergeCommitSHA: v75github.Ptr(mergeSHA),
+		Commits:        v75github.Ptr(1),
+		Head:           &v75github.PullRequestBranch{SHA: v75github.Ptr(mergeSHA)},
+	}
+	for _, label := range labels {
+		pr.Labels = append(pr.Labels, &v75github.Label{Name: v75github.Ptr(label)})
+	}
+	fake.AddPR(pr, mk, &v75github.RepositoryCommit{SHA: v75github.Ptr(mergeSHA)})
+	return fake
+}
+
+// backPorter clones the workspace and returns a backPorter operating on it with the given fake GitHub API.
+//
+// The inputs are initialized with the action defaults and can be adjusted by the given function.
+func (r *testRepo) backPorter(api github.API, adjust func(*Input)) *backPorter {
+	_ = os.RemoveAll(r.workspace)
+	r.git(filepath.Dir(r.workspace), "clone", r.origin, r.workspace)
+
+	in := &Input{
+		GitHubToken:              "token",
+		Committer:                "Backbot",
+		Email:                    "backbot@example.com",
+		Title:                    "[Backport ${target_branch}] ${original_pr_title}",
+		Description:              "Backport of #${original_pr_number} to ${target_branch}.",
+		LabelPattern:             `^backport-to-(support/\d+\.\d+)$`,
+		SupportedBranchesPattern: `^support/\d+\.\d+$`,
+		AllSupportedLabel:        "backport-to-all-supported",
+		SinceLabelPattern:        `^backport-since-(\d+(?:\.\d+)*)$`,
+		ConflictHandling:         ConflictHandlingAbort,
+		StrategyFallback:         ConflictHandlingAbort,
+		MergeCommitHandling:      MergeCommitHandlingSkip,
+
+		ExistingBackportHandling: ExistingBackportHandlingSkip,
+		BranchNameTemplate:       "backport-${original_pr_number}-to-${target_branch}",
+		CommentTemplate:          "{{ .Message }}",
+		AutoMergeMethod:          AutoMergeMethodMerge,
+	}
+	if adjust != nil {
+		adjust(in)
+	}
+	require.NoError(r.t, in.Validate())
+
+	return &backPorter{
+		github: api,
+		git:    git.NewGit(&githubactions.GitHubContext{Workspace: r.workspace}),
+		config: in,
+	}
+}
@@ -0,0 +1,85 @@
+package backport
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sethvargo/go-githubactions"
+)
+
+// commandName is the slash command that triggers a backport from a pull request comment.
+const commandName = "/backport"
+
+// parseCommand extracts the target branches from `/backport <branch>...` slash commands in a comment body.
+//
+// The command must be at the beginning of a line but can appear anywhere in the comment. Branches can be
+// separated by whitespaces or commas, and the branches of multiple commands in the same comment are merged.
+// Commands within fenced code blocks are ignored.
+//
+// It returns the deduplicated list of requested branches and whether the comment contains any command at all.
+func parseCommand(body string) ([]string, bool) {
+	var branches []string
+	var found, inCodeBlock bool
+	for line := range strings.Lines(body) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if inCodeBlock || len(fields) == 0 || fields[0] != commandName {
+			continue
+		}
+		found = true
+		for _, branch := range fields[1:] {
+			if !slices.Contains(branches, branch) {
+				branches = append(branches, branch)
+			}
+		}
+	}
+	return branches, found
+}
+
+// getCommandTargetRefs determines the target branches requested by a `/backport` comment on the source PR.
+//
+// The comment author must have write access to the repository, otherwise the command is rejected with
+// a comment explaining why. It returns the target branches that exist in the repository, or an empty
+// slice if none of them do or the command is rejected. In both cases, the source PR is commented on.
+func (b *backPorter) getCommandTargetRefs(ctx context.Context, srcPrNumber int64, branches []string) ([]string, error) {
+	_, author, err := b.github.GetComment()
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := b.github.HasWriteAccess(ctx, author)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permissions of user %s: %w", author, err)
+	}
+	if !allowed {
+		githubactions.Warningf("User %s is not allowed to trigger backports, ignoring %s command.", author, commandName)
+		return nil, b.comment(ctx, srcPrNumber, commentFailure, nil, fmt.Sprintf(
+			"⚠️ @%s, only users with write access to this repository can trigger backports.", author,
+		))
+	}
+
+	if len(branches) == 0 {
+		githubactions.Warningf("No target branches given to the %s command.", commandName)
+		return nil, b.comment(ctx, srcPrNumber, commentFailure, nil, fmt.Sprintf(
+			"⚠️ No target branches given. Usage: `%s <branch>...`, e.g. `%[1]s support/2.15 support/2.14`.", commandName,
+		))
+	}
+
+	githubactions.Infof("User %s requested backport to branch(es) %v", author, branches)
+
+	refs := b.fetchTargetRefs(ctx, branches)
+	if len(refs) == 0 {
+		return nil, b.comment(ctx, srcPrNumber, commentFailure, nil, fmt.Sprintf(
+			"⚠️ None of the requested branch(es) `%s` exist in this repository, skipping backport.",
+			strings.Join(branches, "`, `"),
+		))
+	}
+	return refs, nil
+}
@@ -0,0 +1,43 @@
+package backport
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		branches []string
+		found    bool
+	}{
+		{name: "NoCommand", body: "LGTM, thanks!"},
+		{name: "NotAtLineStart", body: "Please run /backport support/2.15"},
+		{name: "WithoutBranches", body: "/backport", found: true},
+		{name: "SingleBranch", body: "/backport support/2.15", branches: []string{"support/2.15"}, found: true},
+		{
+			name:     "MultipleBranches",
+			body:     "/backport support/2.15 support/2.14,support/2.13",
+			branches: []string{"support/2.15", "support/2.14", "support/2.13"},
+			found:    true,
+		},
+		{
+			name:     "MultipleCommands",
+			body:     "Thanks!\n/backport support/2.15\r\n  /backport support/2.14 support/2.15\n",
+			branches: []string{"support/2.15", "support/2.14"},
+			found:    true,
+		},
+		{name: "QuotedCommand", body: "> /backport support/2.15"},
+		{name: "CodeBlock", body: "```\n/backport support/2.15\n```"},
+		{name: "Prefix", body: "/backports support/2.15"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			branches, found := parseCommand(tt.body)
+			require.Equal(t, tt.found, found)
+			require.Equal(t, tt.branches, branches)
+		})
+	}
+}
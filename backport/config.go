@@ -0,0 +1,115 @@
+package backport
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goccy/go-yaml"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// RepoConfigVersion is the version of the repository config file format supported by backbot.
+const RepoConfigVersion = 1
+
+// Policy holds the backport settings that can be defined in the repository config file.
+//
+// All fields are optional, and only the ones that are set override the corresponding action inputs.
+type Policy struct {
+	Title             *string  `yaml:"pr_title"`
+	Description       *string  `yaml:"pr_description"`
+	CopyLabelsPattern *string  `yaml:"copy_labels_pattern"`
+	ConflictHandling  *string  `yaml:"conflict_handling"`
+	StrategyOptions   []string `yaml:"strategy_options"`
+	StrategyFallback  *string  `yaml:"strategy_fallback"`
+	Reviewers         []string `yaml:"reviewers"`
+	ReviewFrom        []string `yaml:"review_from"`
+	AssignAuthor      *bool    `yaml:"assign_author"`
+	CopyAssignees     *bool    `yaml:"copy_assignees"`
+	MilestoneTemplate *string  `yaml:"milestone_template"`
+	CopyProjects      *bool    `yaml:"copy_projects"`
+	AutoMerge         *bool    `yaml:"auto_merge"`
+	AutoMergeMethod   *string  `yaml:"auto_merge_method"`
+}
+
+// Rule is an additional label pattern with its own settings, which apply to the backports requested by its labels.
+//
+// Like the LabelPattern, the label pattern of a rule must have a capturing group for the target branch. The settings
+// of a rule take precedence over all other settings, including the ones of the Branches entries.
+type Rule struct {
+	LabelPattern string `yaml:"label_pattern"`
+
+	Policy `yaml:",inline"`
+}
+
+// RepoConfig represents the versioned config file within the repository, usually `.github/backbot.yml`.
+//
+// The top-level settings take precedence over the action inputs, and the settings of the Branches
+// entries take precedence over the top-level settings for backports to the respective branches.
+type RepoConfig struct {
+	// Version is the version of the config file format and must be set to [RepoConfigVersion].
+	Version int `yaml:"version"`
+
+	LabelPattern             *string `yaml:"label_pattern"`
+	MergeCommitHandling      *string `yaml:"merge_commit_handling"`
+	SupportedBranchesPattern *string `yaml:"supported_branches_pattern"`
+	AllSupportedLabel        *string `yaml:"all_supported_label"`
+	SinceLabelPattern        *string `yaml:"since_label_pattern"`
+
+	Policy `yaml:",inline"`
+
+	// BranchAliases maps the branch names captured by the LabelPattern to the actual target branches, so that
+	// labels like `backport-stable` keep working when the branch they refer to changes with each release.
+	BranchAliases map[string]string `yaml:"branch_aliases"`
+
+	// Branches maps target branch names to their specific settings.
+	Branches map[string]Policy `yaml:"branches"`
+
+	// Rules are additional label patterns with their own settings, which are matched in order before the LabelPattern.
+	Rules []Rule `yaml:"rules"`
+}
+
+// ParseRepoConfig parses the given YAML content into a [RepoConfig].
+//
+// Unknown fields are rejected to catch typos early. Returns the parsed config or an error if the
+// content is not valid YAML or has an unsupported version.
+func ParseRepoConfig(data []byte) (*RepoConfig, error) {
+	var cfg RepoConfig
+	if err := yaml.UnmarshalWithOptions(data, &cfg, yaml.DisallowUnknownField()); err != nil {
+		return nil, err
+	}
+	if cfg.Version != RepoConfigVersion {
+		return nil, fmt.Errorf("unsupported config version %d, expected %d", cfg.Version, RepoConfigVersion)
+	}
+	return &cfg, nil
+}
+
+// loadRepoConfig loads the repository config file from the default branch and merges it into the inputs.
+//
+// It's not an error if the config file doesn't exist, in which case the inputs are used as is. Otherwise,
+// the merged inputs are validated again, and any validation errors are returned.
+func (b *backPorter) loadRepoConfig(ctx context.Context) error {
+	if b.config.ConfigFile == "" {
+		return nil
+	}
+
+	data, err := b.github.GetFileContents(ctx, b.config.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to fetch config file %s: %w", b.config.ConfigFile, err)
+	}
+	if data == nil {
+		githubactions.Infof("Config file %s does not exist, using action inputs only", b.config.ConfigFile)
+		return nil
+	}
+
+	cfg, err := ParseRepoConfig(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", b.config.ConfigFile, err)
+	}
+	githubactions.Infof("Merging config file %s into action inputs", b.config.ConfigFile)
+
+	b.config.ApplyRepoConfig(cfg)
+	if err := b.config.Validate(); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", b.config.ConfigFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package backport
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepoConfig(t *testing.T) {
+	newInput := func() *Input {
+		return &Input{
+			GitHubToken:              "token",
+			Committer:                "committer",
+			Email:                    "email",
+			Title:                    "title",
+			Description:              "description",
+			LabelPattern:             "label-pattern",
+			SupportedBranchesPattern: `^support/\d+\.\d+$`,
+			AllSupportedLabel:        "backport-to-all-supported",
+			SinceLabelPattern:        `^backport-since-(\d+(?:\.\d+)*)$`,
+			ConflictHandling:         ConflictHandlingAbort,
+			MergeCommitHandling:      MergeCommitHandlingSkip,
+
+			ExistingBackportHandling: ExistingBackportHandlingSkip,
+			BranchNameTemplate:       "backport-${original_pr_number}-to-${target_branch}",
+			CommentTemplate:          "{{ .Message }}",
+			AutoMergeMethod:          AutoMergeMethodMerge,
+		}
+	}
+
+	t.Run("Precedence", func(t *testing.T) {
+		cfg, err := ParseRepoConfig([]byte(`
+version: 1
+label_pattern: ^backport-(\S+)$
+pr_title: config title
+reviewers: [alice, org/team]
+review_from: [author]
+branches:
+  support/2.14:
+    conflict_handling: draft
+    reviewers: [bob]
+    review_from: [approvers, merger]
+    assign_author: true
+`))
+		require.NoError(t, err)
+
+		input := newInput()
+		input.ApplyRepoConfig(cfg)
+		require.NoError(t, input.Validate())
+		require.Equal(t, `^backport-(\S+)$`, input.LabelPattern)
+		require.Equal(t, "config title", input.Title)
+		require.Equal(t, "description", input.Description)
+		require.Equal(t, []string{"alice", "org/team"}, input.Reviewers)
+		require.Equal(t, []string{ReviewFromAuthor}, input.ReviewFrom)
+		require.False(t, input.AssignAuthor)
+		require.Equal(t, ConflictHandlingAbort, input.ConflictHandling)
+
+		require.Same(t, input, input.ForBranch("support/2.15"))
+
+		branchInput := input.ForBranch("support/2.14")
+		require.Equal(t, "config title", branchInput.Title)
+		require.Equal(t, []string{"bob"}, branchInput.Reviewers)
+		require.Equal(t, []string{ReviewFromApprovers, ReviewFromMerger}, branchInput.ReviewFrom)
+		require.True(t, branchInput.AssignAuthor)
+		require.Equal(t, ConflictHandlingDraft, branchInput.ConflictHandling)
+	})
+
+	t.Run("InvalidBranchSettings", func(t *testing.T) {
+		cfg, err := ParseRepoConfig([]byte("version: 1\nbranches:\n  support/2.14:\n    conflict_handling: whatever\n"))
+		require.NoError(t, err)
+
+		input := newInput()
+		input.ApplyRepoConfig(cfg)
+		require.ErrorContains(t, input.Validate(), "branch support/2.14")
+	})
+
+	t.Run("BranchAliases", func(t *testing.T) {
+		cfg, err := ParseRepoConfig([]byte("version: 1\nbranch_aliases:\n  stable: support/2.15\n  lts: support/2.12\n"))
+		require.NoError(t, err)
+
+		input := newInput()
+		input.ApplyRepoConfig(cfg)
+		require.NoError(t, input.Validate())
+		require.Equal(t, map[string]string{"stable": "support/2.15", "lts": "support/2.12"}, input.branchAliases)
+
+		cfg, err = ParseRepoConfig([]byte("version: 1\nbranch_aliases:\n  stable: support..2.15\n"))
+		require.NoError(t, err)
+		input.ApplyRepoConfig(cfg)
+		require.ErrorContains(t, input.Validate(), "branch alias stable")
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		cfg, err := ParseRepoConfig([]byte(`
+version: 1
+reviewers: [alice]
+branches:
+  support/2.14:
+    conflict_handling: markers
+    reviewers: [bob]
+rules:
+  - label_pattern: ^try-backport-to-(\S+)$
+    conflict_handling: draft
+    pr_title: try title
+`))
+		require.NoError(t, err)
+
+		input := newInput()
+		input.ApplyRepoConfig(cfg)
+		require.NoError(t, input.Validate())
+		require.Len(t, input.rules, 1)
+		require.True(t, input.requestsBackport("try-backport-to-support/2.14"))
+
+		ruleInput := input.forTarget(target{ref: "support/2.15", rule: input.rules[0]})
+		require.Equal(t, ConflictHandlingDraft, ruleInput.ConflictHandling)
+		require.Equal(t, "try title", ruleInput.Title)
+		require.Equal(t, []string{"alice"}, ruleInput.Reviewers)
+
+		branchInput := input.forTarget(target{ref: "support/2.14", rule: input.rules[0]})
+		require.Equal(t, ConflictHandlingDraft, branchInput.ConflictHandling, "rules must take precedence over branches")
+		require.Equal(t, []string{"bob"}, branchInput.Reviewers)
+		require.Same(t, input.ForBranch("support/2.14"), input.forTarget(target{ref: "support/2.14"}))
+
+		cfg, err = ParseRepoConfig([]byte("version: 1\nrules:\n  - label_pattern: ^try-backport$\n"))
+		require.NoError(t, err)
+		input.ApplyRepoConfig(cfg)
+		require.ErrorContains(t, input.Validate(), "rule 1: label_pattern must have a capturing group")
+	})
+
+	t.Run("UnsupportedVersion", func(t *testing.T) {
+		_, err := ParseRepoConfig([]byte("pr_title: title\n"))
+		require.ErrorContains(t, err, "unsupported config version 0")
+	})
+
+	t.Run("UnknownField", func(t *testing.T) {
+		_, err := ParseRepoConfig([]byte("version: 1\npr_titel: title\n"))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,208 @@
+package backport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/yhabteab/backbot/git"
+)
+
+// resolvedConflict records a commit whose conflict was resolved by retrying with a merge strategy option.
+type resolvedConflict struct {
+	commitSHA string // The commit that conflicted.
+	option    string // The merge strategy option that applied the commit without conflicts.
+}
+
+// describeResolved returns a human-readable, comma-separated description of the given resolved conflicts.
+func describeResolved(resolved []resolvedConflict) string {
+	var descriptions []string
+	for _, rc := range resolved {
+		descriptions = append(descriptions, fmt.Sprintf("`-X %s` (`%.7s`)", rc.option, rc.commitSHA))
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// describeConflicts returns a Markdown note for the body of the backport PR of the given result, describing
+// the conflicts encountered while cherry-picking its commits, or an empty string if there were none.
+func describeConflicts(result *backportResult) string {
+	var sb strings.Builder
+	if len(result.resolved) != 0 {
+		sb.WriteString("\n\n---\n⚠️ Conflicts were resolved automatically by retrying with merge strategy options, please review carefully:\n")
+		for _, rc := range result.resolved {
+			_, _ = fmt.Fprintf(&sb, "- %s: `--strategy-option=%s`\n", rc.commitSHA, rc.option)
+		}
+	}
+	if result.markers {
+		_, _ = fmt.Fprintf(
+			&sb, "\n\n---\n⚠️ Cherry-picking commit %s conflicts. The following files were committed including their "+
+				"conflict markers, which must be resolved before merging:\n",
+			result.pending[0],
+		)
+		sb.WriteString(listConflictedFiles(result.conflictedFiles))
+		if len(result.pending) > 1 {
+			_, _ = fmt.Fprintf(
+				&sb, "\nThe following commits haven't been cherry-picked yet: %s\n", strings.Join(result.pending[1:], ", "),
+			)
+		}
+	}
+	return sb.String()
+}
+
+// listConflictedFiles returns a Markdown list of the given conflicting files, including the kind of conflict
+// and the number of conflict hunks, if any.
+func listConflictedFiles(files []git.ConflictedFile) string {
+	var sb strings.Builder
+	for _, file := range files {
+		switch file.Hunks {
+		case 0:
+			_, _ = fmt.Fprintf(&sb, "- `%s` (%s)\n", file.Path, file.Type)
+		case 1:
+			_, _ = fmt.Fprintf(&sb, "- `%s` (%s): 1 conflict\n", file.Path, file.Type)
+		default:
+			_, _ = fmt.Fprintf(&sb, "- `%s` (%s): %d conflicts\n", file.Path, file.Type, file.Hunks)
+		}
+	}
+	return sb.String()
+}
+
+// cherryPickCommit cherry-picks the given commit, creating a draft commit if it conflicts as per the given
+// conflict handling, which is either draft or markers. The conflicted files are recorded in the given result.
+//
+// Returns whether the commit conflicts, or an error if the operation fails.
+func (b *backPorter) cherryPickCommit(ctx context.Context, handling, commitSHA string, result *backportResult) (bool, error) {
+	if handling == ConflictHandlingMarkers {
+		files, err := b.git.CherryPickWithMarkers(ctx, commitSHA)
+		if err != nil {
+			return false, err
+		}
+		result.conflictedFiles, result.markers = files, len(files) != 0
+		return result.markers, nil
+	}
+
+	if err := b.git.CherryPick(ctx, true, commitSHA); err != nil {
+		if git.IsConflictErr(err) {
+			result.conflictedFiles = git.ConflictedFiles(err)
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// retryWithStrategyOptions retries cherry-picking the given commit with each of the configured merge strategy options.
+//
+// Returns the first option that applies the commit without conflicts, or the error of the last attempt.
+func (b *backPorter) retryWithStrategyOptions(ctx context.Context, cfg *Input, commitSHA string) (string, error) {
+	var err error
+	for _, option := range cfg.StrategyOptions {
+		githubactions.Infof("Retrying cherry-pick of commit %s with merge strategy option %s", commitSHA, option)
+		if err = b.git.CherryPickWithOption(ctx, option, commitSHA); err == nil {
+			githubactions.Infof("Merge strategy option %s resolved the conflict of commit %s", option, commitSHA)
+			return option, nil
+		}
+		if !git.IsConflictErr(err) {
+			return "", err
+		}
+	}
+	githubactions.Warningf("None of the merge strategy options %v resolved the conflict of commit %s", cfg.StrategyOptions, commitSHA)
+	return "", err
+}
+
+// abortBackport aborts the backport described by the given result due to the given cherry-pick error.
+//
+// The source PR is commented on, listing the conflicting files if the error is a conflict.
+// Returns the updated result.
+func (b *backPorter) abortBackport(ctx context.Context, srcPr *v75github.PullRequest, result *backportResult, err error) *backportResult {
+	githubactions.Errorf("Failed to cherry pick commits: %v", err)
+	result.outcome, result.details = outcomeFailed, "failed to cherry-pick the commits"
+	if git.IsConflictErr(err) {
+		result.outcome, result.details, result.conflict = outcomeAborted, "cherry-picking the commits conflicts", true
+		result.conflictedFiles = git.ConflictedFiles(err)
+	}
+
+	srcPrNum := int64(srcPr.GetNumber())
+	msg := fmt.Sprintf(
+		"⚠️ Conflict occurred while backporting to branch %s. Aborting backport as per configuration. %s",
+		result.target, seeLogs(b.github.WorkflowRunURL()),
+	)
+	if len(result.conflictedFiles) != 0 {
+		commit := "the commits"
+		if sha := git.ConflictingCommit(err); sha != "" {
+			commit = "commit " + sha
+		}
+		msg += fmt.Sprintf("\n\nCherry-picking %s conflicts in the following files:\n%s", commit, listConflictedFiles(result.conflictedFiles))
+	}
+	if err := b.comment(ctx, srcPrNum, commentConflict, result, msg); err != nil {
+		githubactions.Errorf("Failed to create comment on PR #%d: %v", srcPrNum, err)
+	}
+	return result
+}
+
+// draftBackport creates a draft PR for the backport described by the given result, after cherry-picking the
+// first of the given remaining commits conflicted and a draft commit was created instead. The open PR of the
+// given existing backport, if any, is reused rather than creating another one. A reused PR that is ready for
+// review is converted to a draft before pushing, so that it cannot be merged while commits are missing.
+//
+// Both the source PR and the draft PR are commented on with the steps to resolve the conflict manually.
+// Returns the updated result.
+func (b *backPorter) draftBackport(ctx context.Context, cfg *Input, srcPr *v75github.PullRequest, backportRef string, remaining []string, result *backportResult, existing *existingBackport) *backportResult {
+	result.conflict, result.pending = true, remaining
+	githubactions.Warningf(
+		"Conflict occurred while cherry-picking commit %s to branch %s, trying to prepare for manual backport.",
+		remaining[0], result.target,
+	)
+
+	pr, err := makeNewPullRequest(cfg, srcPr, result, backportRef, true)
+	if err != nil {
+		githubactions.Errorf("Failed to prepare draft PR for backport branch %s: %v", backportRef, err)
+		result.outcome, result.details = outcomeFailed, "failed to render the pull request templates"
+		return result
+	}
+	if existing != nil && existing.pr != nil && !existing.pr.GetDraft() {
+		if err := b.convertToDraft(ctx, existing.pr); err != nil {
+			githubactions.Errorf("Failed to convert backport PR #%d to a draft: %v", existing.pr.GetNumber(), err)
+			result.outcome, result.details = outcomeFailed, fmt.Sprintf("failed to convert #%d to a draft", existing.pr.GetNumber())
+			return result
+		}
+		existing.pr.Draft = v75github.Ptr(true)
+	}
+	// Push the backport branch with the draft commit to remote.
+	if err := b.push(ctx, backportRef, existing.forcePush()); err != nil {
+		githubactions.Errorf("Failed to push backport branch %s: %v", backportRef, err)
+		result.outcome, result.details = outcomeFailed, fmt.Sprintf("failed to push `%s`", backportRef)
+		return result
+	}
+	newPr, _, err := b.openPR(ctx, existing, result, pr)
+	if err != nil {
+		githubactions.Errorf("Failed to create draft PR for backport branch %s: %v", backportRef, err)
+		result.outcome, result.details = outcomeFailed, "failed to create the draft pull request"
+		return result
+	}
+	result.outcome, result.pr = outcomeDraft, newPr
+	result.details = fmt.Sprintf("cherry-picking `%.7s` conflicts", remaining[0])
+
+	msg := fmt.Sprintf(
+		"⚠️ Backporting commit %s to branch `%s` causes a conflict. Created draft PR for manual resolution.\n\n",
+		remaining[0], result.target,
+	)
+	if len(result.conflictedFiles) != 0 {
+		msg += fmt.Sprintf("The following files conflict:\n%s\n", listConflictedFiles(result.conflictedFiles))
+	}
+	if result.markers {
+		result.details += fmt.Sprintf(" in %d file(s), committed with conflict markers", len(result.conflictedFiles))
+		msg += "The draft PR contains the conflict markers, so they can be resolved right in the pull request. " +
+			"Alternatively, redo the backport manually:\n\n"
+	}
+	msg += fmt.Sprintf("### Manual Backport Steps\n```bash\n%s\n```\n", listManualSteps(backportRef, remaining))
+	srcPrNum := int64(srcPr.GetNumber())
+	if err := b.comment(ctx, srcPrNum, commentConflict, result, msg); err != nil {
+		githubactions.Errorf("Failed to create comment on PR #%d: %v", srcPrNum, err)
+	}
+	if err := b.comment(ctx, int64(newPr.GetNumber()), commentConflict, result, msg); err != nil {
+		githubactions.Errorf("Failed to create comment on draft PR #%d: %v", newPr.GetNumber(), err)
+	}
+	return result
+}
@@ -0,0 +1,267 @@
+package backport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/yhabteab/backbot/github"
+)
+
+// plannedBackport records what a dry run would have done for a single target branch.
+type plannedBackport struct {
+	target      string                    // The target branch of the backport.
+	backportRef string                    // The name of the backport branch.
+	pushed      bool                      // Whether the backport branch would have been pushed.
+	forced      bool                      // Whether the backport branch would have been force-pushed.
+	pr          *v75github.NewPullRequest // The backport PR that would have been created, if any.
+	reused      *v75github.PullRequest    // The existing backport PR that would have been reused, if any.
+	toDraft     bool                      // Whether the reused backport PR would have been converted to a draft.
+	toReady     bool                      // Whether the reused backport PR would have been marked ready for review.
+	labels      []string                  // The labels that would have been added to the backport PR.
+	reviewers   []string                  // The reviewers that would have been requested for the backport PR.
+	assignees   []string                  // The users that would have been assigned to the backport PR.
+	milestone   string                    // The milestone that would have been set on the backport PR, if any.
+	projects    []string                  // The titles of the projects the backport PR would have been added to.
+	autoMerge   string                    // The method auto-merge would have been enabled with, if any.
+}
+
+// plannedComment records a comment that a dry run would have posted.
+type plannedComment struct {
+	issueNumber int64 // The number of the issue or PR to comment on, or 0 for the new backport PR.
+	body        string
+}
+
+// dryRunPlan collects everything a dry run would have done instead of doing it.
+//
+// The outcome of each backport is recorded in the [report] as usual, the plan only complements it with the
+// side effects that were skipped. All methods are no-ops on a nil plan, so that callers don't need to check
+// whether dry-run mode is enabled.
+type dryRunPlan struct {
+	backports []*plannedBackport
+	comments  []plannedComment
+}
+
+// addBackport starts recording the backport to a new target branch.
+func (p *dryRunPlan) addBackport(target, backportRef string) {
+	if p != nil {
+		p.backports = append(p.backports, &plannedBackport{target: target, backportRef: backportRef})
+	}
+}
+
+// current returns the backport to the target branch that is currently being processed.
+func (p *dryRunPlan) current() *plannedBackport {
+	if p == nil || len(p.backports) == 0 {
+		return &plannedBackport{} // Discard whatever is recorded before the first backport.
+	}
+	return p.backports[len(p.backports)-1]
+}
+
+// String renders the plan as a Markdown section to be appended to the [report] in the job summary.
+func (p *dryRunPlan) String() string {
+	var sb strings.Builder
+	sb.WriteString("### Dry run\n\nThis was a dry run, nothing was pushed or posted. Backbot would have done the following:\n\n")
+	if len(p.backports) == 0 {
+		sb.WriteString("No backports would have been attempted.\n\n")
+	}
+
+	for _, bp := range p.backports {
+		_, _ = fmt.Fprintf(&sb, "#### `%s`\n\n", bp.target)
+		_, _ = fmt.Fprintf(&sb, "- Backport branch: `%s` (pushed: %t, forced: %t)\n", bp.backportRef, bp.pushed, bp.forced)
+		switch {
+		case bp.reused != nil && bp.toDraft:
+			_, _ = fmt.Fprintf(&sb, "- Pull request: #%d (existing, converted to draft)\n", bp.reused.GetNumber())
+		case bp.reused != nil && bp.toReady:
+			_, _ = fmt.Fprintf(&sb, "- Pull request: #%d (existing, marked ready for review)\n", bp.reused.GetNumber())
+		case bp.reused != nil:
+			_, _ = fmt.Fprintf(&sb, "- Pull request: #%d (existing)\n", bp.reused.GetNumber())
+		case bp.pr != nil:
+			_, _ = fmt.Fprintf(&sb, "- Pull request: **%s** (draft: %t)\n", bp.pr.GetTitle(), bp.pr.GetDraft())
+		default:
+			sb.WriteString("- Pull request: none\n\n")
+			continue
+		}
+		if len(bp.labels) != 0 {
+			_, _ = fmt.Fprintf(&sb, "- Labels: `%s`\n", strings.Join(bp.labels, "`, `"))
+		}
+		if len(bp.reviewers) != 0 {
+			_, _ = fmt.Fprintf(&sb, "- Reviewers: `%s`\n", strings.Join(bp.reviewers, "`, `"))
+		}
+		if len(bp.assignees) != 0 {
+			_, _ = fmt.Fprintf(&sb, "- Assignees: `%s`\n", strings.Join(bp.assignees, "`, `"))
+		}
+		if bp.milestone != "" {
+			_, _ = fmt.Fprintf(&sb, "- Milestone: `%s`\n", bp.milestone)
+		}
+		if len(bp.projects) != 0 {
+			_, _ = fmt.Fprintf(&sb, "- Projects: `%s`\n", strings.Join(bp.projects, "`, `"))
+		}
+		if bp.autoMerge != "" {
+			_, _ = fmt.Fprintf(&sb, "- Auto-merge: `%s`\n", bp.autoMerge)
+		}
+		if bp.pr == nil {
+			sb.WriteString("\n")
+			continue
+		}
+		_, _ = fmt.Fprintf(&sb, "\n<details><summary>Description</summary>\n\n%s\n\n</details>\n\n", bp.pr.GetBody())
+	}
+
+	if len(p.comments) != 0 {
+		sb.WriteString("#### Comments\n\n")
+	}
+	for _, comment := range p.comments {
+		target := "the new backport PR"
+		if comment.issueNumber != 0 {
+			target = fmt.Sprintf("#%d", comment.issueNumber)
+		}
+		_, _ = fmt.Fprintf(&sb, "On %s:\n\n> %s\n\n", target, strings.ReplaceAll(comment.body, "\n", "\n> "))
+	}
+	return sb.String()
+}
+
+// comment creates a comment on the given issue or PR, or records it in dry-run mode.
+//
+// The given message is rendered with the CommentTemplate first, which has access to the kind of the comment and the
+// backport result it refers to, if any. If the template renders to blank text, the comment is not created at all,
+// and if it cannot be rendered, the message is commented as is.
+func (b *backPorter) comment(ctx context.Context, issueNumber int64, kind commentKind, result *backportResult, body string) error {
+	if b.config.CommentTemplate != "" {
+		data := newTemplateData(b.report.sourcePr, result)
+		data.Kind, data.Message = kind, body
+		rendered, err := renderTemplate("comment_template", b.config.CommentTemplate, data)
+		switch {
+		case err != nil:
+			githubactions.Warningf("%v, commenting the default message instead", err)
+		case strings.TrimSpace(rendered) == "":
+			githubactions.Infof("Comment template renders the %s comment on #%d blank, not commenting", kind, issueNumber)
+			return nil
+		default:
+			body = rendered
+		}
+	}
+
+	if b.plan != nil {
+		githubactions.Infof("[dry run] Would comment on #%d: %s", issueNumber, body)
+		b.plan.comments = append(b.plan.comments, plannedComment{issueNumber: issueNumber, body: body})
+		return nil
+	}
+	return b.github.CreateComment(ctx, issueNumber, body)
+}
+
+// push pushes the given backport branch to the remote, or records it in dry-run mode.
+//
+// If force is set, the branch is force-pushed, overwriting an existing one of a previous run.
+func (b *backPorter) push(ctx context.Context, ref string, force bool) error {
+	if b.plan != nil {
+		githubactions.Infof("[dry run] Would push branch %s (force: %t)", ref, force)
+		b.plan.current().pushed, b.plan.current().forced = true, force
+		return nil
+	}
+	if force {
+		return b.git.ForcePush(ctx, ref)
+	}
+	return b.git.Push(ctx, ref)
+}
+
+// createPR creates the given backport PR, or records it in dry-run mode.
+//
+// In dry-run mode, the returned PR is made up from the given one and has no number.
+func (b *backPorter) createPR(ctx context.Context, pr *v75github.NewPullRequest) (*v75github.PullRequest, error) {
+	if b.plan != nil {
+		githubactions.Infof("[dry run] Would create PR '%s' from %s to %s", pr.GetTitle(), pr.GetHead(), pr.GetBase())
+		b.plan.current().pr = pr
+		return &v75github.PullRequest{
+			Title: pr.Title,
+			Body:  pr.Body,
+			Draft: pr.Draft,
+			Head:  &v75github.PullRequestBranch{Ref: pr.Head},
+			Base:  &v75github.PullRequestBranch{Ref: pr.Base},
+		}, nil
+	}
+	return b.github.CreatePR(ctx, pr)
+}
+
+// updatePR updates the title and body of the PR to the ones of the given new PR, or only logs it in dry-run mode.
+func (b *backPorter) updatePR(ctx context.Context, pr *v75github.PullRequest, newPr *v75github.NewPullRequest) error {
+	if b.plan != nil {
+		githubactions.Infof("[dry run] Would update title and body of PR #%d to '%s'", pr.GetNumber(), newPr.GetTitle())
+		return nil
+	}
+	return b.github.UpdatePR(ctx, pr, newPr.GetTitle(), newPr.GetBody())
+}
+
+// labelPR adds the given labels to the PR, or records them in dry-run mode.
+func (b *backPorter) labelPR(ctx context.Context, pr *v75github.PullRequest, labels ...string) error {
+	if b.plan != nil {
+		b.plan.current().labels = labels
+		return nil
+	}
+	_, err := b.github.LabelPR(ctx, pr, labels...)
+	return err
+}
+
+// requestReviewers requests a review from the given reviewers on the PR, or records them in dry-run mode.
+func (b *backPorter) requestReviewers(ctx context.Context, pr *v75github.PullRequest, reviewers ...string) error {
+	if b.plan != nil {
+		b.plan.current().reviewers = reviewers
+		return nil
+	}
+	return b.github.RequestReviewers(ctx, pr, reviewers...)
+}
+
+// assignPR assigns the given users to the PR, or records them in dry-run mode.
+func (b *backPorter) assignPR(ctx context.Context, pr *v75github.PullRequest, assignees ...string) error {
+	if b.plan != nil {
+		b.plan.current().assignees = assignees
+		return nil
+	}
+	return b.github.AssignPR(ctx, pr, assignees...)
+}
+
+// setMilestone sets the given milestone on the PR, or records it in dry-run mode.
+func (b *backPorter) setMilestone(ctx context.Context, pr *v75github.PullRequest, milestone *v75github.Milestone) error {
+	if b.plan != nil {
+		b.plan.current().milestone = milestone.GetTitle()
+		return nil
+	}
+	return b.github.SetMilestone(ctx, pr, milestone)
+}
+
+// addToProject adds the PR to the given project, or records it in dry-run mode.
+func (b *backPorter) addToProject(ctx context.Context, pr *v75github.PullRequest, project github.Project) error {
+	if b.plan != nil {
+		current := b.plan.current()
+		current.projects = append(current.projects, project.Title)
+		return nil
+	}
+	return b.github.AddToProject(ctx, pr, project)
+}
+
+// convertToDraft converts the PR to a draft, or records it in dry-run mode.
+func (b *backPorter) convertToDraft(ctx context.Context, pr *v75github.PullRequest) error {
+	if b.plan != nil {
+		b.plan.current().toDraft = true
+		return nil
+	}
+	return b.github.ConvertToDraft(ctx, pr)
+}
+
+// markReadyForReview marks the draft PR as ready for review, or records it in dry-run mode.
+func (b *backPorter) markReadyForReview(ctx context.Context, pr *v75github.PullRequest) error {
+	if b.plan != nil {
+		b.plan.current().toReady = true
+		return nil
+	}
+	return b.github.MarkReadyForReview(ctx, pr)
+}
+
+// enableAutoMerge enables auto-merge with the given method on the PR, or records it in dry-run mode.
+func (b *backPorter) enableAutoMerge(ctx context.Context, pr *v75github.PullRequest, method string) error {
+	if b.plan != nil {
+		b.plan.current().autoMerge = method
+		return nil
+	}
+	return b.github.EnableAutoMerge(ctx, pr, method)
+}
@@ -0,0 +1,446 @@
+package backport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/require"
+	"github.com/yhabteab/backbot/github"
+)
+
+func TestEndToEnd(t *testing.T) {
+	t.Run("Squash", func(t *testing.T) {
+		repo := newTestRepo(t)
+		head := repo.openPR(1, "feature.txt", "library.txt")
+		repo.commit("main", "other.txt", "unrelated\n", "Unrelated change")
+		repo.git(repo.upstream, "merge", "--squash", "pr-1")
+		repo.git(repo.upstream, "commit", "--message", "Add feature (#1)")
+		mergeSHA := repo.push("main")
+
+		srv := newAPIServer(repo)
+		srv.addMergedPR(1, "Add feature", head, mergeSHA, "backport-to-support/1.0", "area/core")
+		srv.run(t, 1, func(in *Input) { in.CopyLabelsPattern = "^area/" })
+		require.Equal(t, []string{"area/core"}, srv.labels[2])
+
+		require.Equal(t, "feature.txt\nlibrary.txt", repo.git(repo.origin, "diff", "--name-only", "support/1.0", "backport-1-to-support/1.0"))
+		require.Equal(t, []string{"Add feature (#1)"}, repo.log("support/1.0", "backport-1-to-support/1.0"))
+		srv.requireBackportPR(t, 1, "support/1.0")
+	})
+
+	t.Run("MergeCommit", func(t *testing.T) {
+		repo := newTestRepo(t)
+		head := repo.openPR(1, "feature.txt", "library.txt")
+		repo.commit("main", "other.txt", "unrelated\n", "Unrelated change")
+		repo.git(repo.upstream, "merge", "--no-ff", "--message", "Merge pull request #1", "pr-1")
+		mergeSHA := repo.push("main")
+
+		srv := newAPIServer(repo)
+		srv.addMergedPR(1, "Add feature", head, mergeSHA, "backport-to-support/1.0")
+		srv.run(t, 1, nil)
+
+		require.Equal(t, "feature.txt\nlibrary.txt", repo.git(repo.origin, "diff", "--name-only", "support/1.0", "backport-1-to-support/1.0"))
+		require.Equal(t, []string{"Change feature.txt", "Change library.txt"}, repo.log("support/1.0", "backport-1-to-support/1.0"))
+		srv.requireBackportPR(t, 1, "support/1.0")
+	})
+
+	t.Run("Rebase", func(t *testing.T) {
+		repo := newTestRepo(t)
+		head := repo.openPR(1, "feature.txt", "library.txt")
+		base := repo.commit("main", "other.txt", "unrelated\n", "Unrelated change")
+		repo.git(repo.upstream, "cherry-pick", base+"..pr-1")
+		mergeSHA := repo.push("main")
+		require.NotEqual(t, head, mergeSHA, "rebasing must rewrite the commits")
+
+		srv := newAPIServer(repo)
+		srv.addMergedPR(1, "Add feature", head, mergeSHA, "backport-to-support/1.0")
+		srv.run(t, 1, nil)
+
+		require.Equal(t, "feature.txt\nlibrary.txt", repo.git(repo.origin, "diff", "--name-only", "support/1.0", "backport-1-to-support/1.0"))
+		require.Equal(t, []string{"Change feature.txt", "Change library.txt"}, repo.log("support/1.0", "backport-1-to-support/1.0"))
+		srv.requireBackportPR(t, 1, "support/1.0")
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.commit("support/1.0", "feature.txt", "diverged\n", "Diverge support branch")
+		head := repo.openPR(1, "feature.txt")
+		repo.git(repo.upstream, "switch", "main")
+		repo.git(repo.upstream, "merge", "--squash", "pr-1")
+		repo.git(repo.upstream, "commit", "--message", "Add feature (#1)")
+		mergeSHA := repo.push("main")
+
+		srv := newAPIServer(repo)
+		srv.addMergedPR(1, "Add feature", head, mergeSHA, "backport-to-support/1.0")
+		runURL := srv.run(t, 1, nil) + "/icinga/backbot/actions/runs/42"
+
+		require.False(t, repo.hasBranch("backport-1-to-support/1.0"))
+		require.Len(t, srv.comments[1], 2)
+		require.Contains(t, srv.comments[1][0], "Conflict occurred while backporting to branch support/1.0")
+		require.Contains(t, srv.comments[1][0], "[workflow run]("+runURL+")")
+		require.Contains(t, srv.comments[1][1], "[workflow run]("+runURL+")")
+	})
+
+	t.Run("Rerun", func(t *testing.T) {
+		repo := newTestRepo(t)
+		head := repo.openPR(1, "feature.txt")
+		repo.git(repo.upstream, "switch", "main")
+		repo.git(repo.upstream, "merge", "--squash", "pr-1")
+		repo.git(repo.upstream, "commit", "--message", "Add feature (#1)")
+		mergeSHA := repo.push("main")
+
+		srv := newAPIServer(repo)
+		srv.addMergedPR(1, "Add feature", head, mergeSHA, "backport-to-support/1.0")
+		srv.run(t, 1, nil)
+		srv.requireBackportPR(t, 1, "support/1.0")
+		srv.run(t, 1, func(in *Input) { in.ExistingBackportHandling = ExistingBackportHandlingForce })
+
+		require.Len(t, srv.prs, 2, "the existing backport PR must be reused")
+		require.Len(t, srv.comments[2], 1)
+		require.Contains(t, srv.comments[2][0], "The backport branch was recreated and force-pushed")
+		require.Len(t, srv.comments[1], 2)
+		require.Contains(t, srv.comments[1][1], "Successfully created backport PR(s)")
+	})
+
+	t.Run("MultipleTargets", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.git(repo.upstream, "push", "origin", "main:support/1.1")
+		head := repo.openPR(1, "feature.txt")
+		repo.git(repo.upstream, "switch", "main")
+		repo.git(repo.upstream, "merge", "--squash", "pr-1")
+		repo.git(repo.upstream, "commit", "--message", "Add feature (#1)")
+		mergeSHA := repo.push("main")
+
+		srv := newAPIServer(repo)
+		srv.addMergedPR(1, "Add feature", head, mergeSHA, "backport-to-support/1.0", "backport-to-support/1.1", "backport-to-support/9.9")
+		srv.run(t, 1, nil)
+
+		srv.requireBackportPR(t, 1, "support/1.0", "support/1.1")
+		require.Equal(t, "feature.txt", repo.git(repo.origin, "diff", "--name-only", "support/1.1", "backport-1-to-support/1.1"))
+	})
+
+	t.Run("AutoMerge", func(t *testing.T) {
+		repo := newTestRepo(t)
+		head := repo.openPR(1, "feature.txt")
+		repo.git(repo.upstream, "switch", "main")
+		repo.git(repo.upstream, "merge", "--squash", "pr-1")
+		repo.git(repo.upstream, "commit", "--message", "Add feature (#1)")
+		mergeSHA := repo.push("main")
+
+		srv := newAPIServer(repo)
+		srv.addMergedPR(1, "Add feature", head, mergeSHA, "backport-to-support/1.0")
+		srv.run(t, 1, func(in *Input) { in.AutoMerge, in.AutoMergeMethod = true, AutoMergeMethodSquash })
+
+		srv.requireBackportPR(t, 1, "support/1.0")
+		require.Equal(t, []map[string]any{{"pr": "PR_2", "method": "SQUASH"}}, srv.graphQL,
+			"the GraphQL API of the server must be used, not the one of github.com")
+		require.Contains(t, srv.comments[1][0], "#2 (auto-merge enabled, squash)")
+	})
+}
+
+// openPR simulates opening the given pull request from a branch named pr-<number> based on main.
+// Every given file is added with a commit of its own.
+//
+// Returns the SHA of the head commit of the pull request.
+func (r *testRepo) openPR(number int, files ...string) string {
+	branch := fmt.Sprintf("pr-%d", number)
+	r.git(r.upstream, "push", "origin", "main:"+branch)
+
+	var head string
+	for _, file := range files {
+		head = r.commit(branch, file, "content of "+file+"\n", "Change "+file)
+	}
+	return head
+}
+
+// push pushes the given branch of the upstream clone to the origin.
+//
+// Returns the SHA of the pushed commit.
+func (r *testRepo) push(branch string) string {
+	r.git(r.upstream, "push", "origin", branch)
+	return r.git(r.upstream, "rev-parse", branch)
+}
+
+// log returns the subjects of the commits in the origin that are reachable from head but not from base,
+// in chronological order.
+func (r *testRepo) log(base, head string) []string {
+	return strings.Split(r.git(r.origin, "log", "--reverse", "--format=%s", base+".."+head), "\n")
+}
+
+// apiServer serves the subset of the GitHub REST API used by backbot for the icinga/backbot repository.
+//
+// Commits and changed files are derived from the origin of the underlying testRepo, while pull requests,
+// comments and labels are kept in memory. The GraphQL API only records the variables of the requests it gets.
+// Requests for any other repository or endpoint fail with 404.
+type apiServer struct {
+	repo *testRepo
+
+	mu       sync.Mutex
+	prs      map[int]*v75github.PullRequest
+	comments map[int][]string
+	labels   map[int][]string
+	graphQL  []map[string]any
+}
+
+// newAPIServer creates a new apiServer for the given testRepo.
+func newAPIServer(repo *testRepo) *apiServer {
+	return &apiServer{
+		repo:     repo,
+		prs:      make(map[int]*v75github.PullRequest),
+		comments: make(map[int][]string),
+		labels:   make(map[int][]string),
+	}
+}
+
+// addMergedPR adds a pull request from the pr-<number> branch to main, which got merged as the given commit.
+func (s *apiServer) addMergedPR(number int, title, headSHA, mergeSHA string, labels ...string) {
+	baseSHA := s.repo.git(s.repo.origin, "merge-base", headSHA, mergeSHA+"^1")
+	pr := &v75github.PullRequest{
+		Number:         v75github.Ptr(number),
+		State:          v75github.Ptr("closed"),
+		Title:          v75github.Ptr(title),
+		Merged:         v75github.Ptr(true),
+		MergeCommitSHA: v75github.Ptr(mergeSHA),
+		Commits:        v75github.Ptr(len(s.commits(baseSHA, headSHA))),
+		ChangedFiles:   v75github.Ptr(len(s.files(baseSHA, headSHA))),
+		Head:           &v75github.PullRequestBranch{Ref: v75github.Ptr(fmt.Sprintf("pr-%d", number)), SHA: v75github.Ptr(headSHA)},
+		Base:           &v75github.PullRequestBranch{Ref: v75github.Ptr("main"), SHA: v75github.Ptr(baseSHA)},
+		HTMLURL:        v75github.Ptr(fmt.Sprintf("https://github.com/icinga/backbot/pull/%d", number)),
+	}
+	for _, label := range labels {
+		pr.Labels = append(pr.Labels, &v75github.Label{Name: v75github.Ptr(label)})
+	}
+	s.prs[number] = pr
+}
+
+// run starts the server and runs backbot against it as if the given pull request had just been closed.
+//
+// The inputs can be adjusted by the given function, see [testRepo.backPorter].
+// Returns the URL of the server, which serves the REST API under /api/v3 and the GraphQL API under /api/graphql.
+func (s *apiServer) run(t *testing.T, prNumber int, adjust func(*Input)) string {
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(srv.Close)
+
+	// The server is not api.github.com, so it's addressed like a GitHub Enterprise Server instance.
+	client, err := github.NewClient(&githubactions.GitHubContext{
+		APIURL:     srv.URL + "/api/v3",
+		ServerURL:  srv.URL,
+		RunID:      42,
+		Repository: "icinga/backbot",
+		EventName:  github.EventPullRequest,
+		Event: map[string]any{
+			"action":       "closed",
+			"pull_request": map[string]any{"number": float64(prNumber)},
+		},
+	}, "token")
+	require.NoError(t, err)
+	require.NoError(t, s.repo.backPorter(client, adjust).Run(context.Background()))
+	return srv.URL
+}
+
+// requireBackportPR asserts that exactly one backport PR was created for each of the given target branches,
+// and that the source PR was commented on referencing all of them.
+func (s *apiServer) requireBackportPR(t *testing.T, srcPrNumber int, targets ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var created []*v75github.PullRequest
+	for number := srcPrNumber + 1; s.prs[number] != nil; number++ {
+		created = append(created, s.prs[number])
+	}
+	require.Len(t, created, len(targets))
+
+	for i, target := range targets {
+		pr := created[i]
+		require.Equal(t, target, pr.GetBase().GetRef())
+		require.Equal(t, fmt.Sprintf("backport-%d-to-%s", srcPrNumber, target), pr.GetHead().GetRef())
+		require.Equal(t, fmt.Sprintf("[Backport %s] %s", target, s.prs[srcPrNumber].GetTitle()), pr.GetTitle())
+		require.False(t, pr.GetDraft())
+	}
+
+	comments := s.comments[srcPrNumber]
+	require.Len(t, comments, 1)
+	require.Contains(t, comments[0], "Successfully created backport PR(s)")
+	for _, pr := range created {
+		require.Contains(t, comments[0], fmt.Sprintf("#%d", pr.GetNumber()))
+	}
+	require.Empty(t, s.labels[srcPrNumber], "labels must not be added to the source PR")
+}
+
+// handler returns the HTTP handler serving the REST API endpoints.
+func (s *apiServer) handler() http.Handler {
+	const repo = "/api/v3/repos/icinga/backbot"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+repo+"/pulls", func(w http.ResponseWriter, r *http.Request) {
+		_, head, _ := strings.Cut(r.URL.Query().Get("head"), ":")
+		base := r.URL.Query().Get("base")
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		prs := []*v75github.PullRequest{}
+		for _, pr := range s.prs {
+			if pr.GetState() == "open" && pr.GetHead().GetRef() == head && pr.GetBase().GetRef() == base {
+				prs = append(prs, pr)
+			}
+		}
+		writeJSON(w, http.StatusOK, prs)
+	})
+	mux.HandleFunc("GET "+repo+"/pulls/{number}", s.withPR(func(w http.ResponseWriter, _ *http.Request, pr *v75github.PullRequest) {
+		writeJSON(w, http.StatusOK, pr)
+	}))
+	mux.HandleFunc("GET "+repo+"/pulls/{number}/commits", s.withPR(func(w http.ResponseWriter, _ *http.Request, pr *v75github.PullRequest) {
+		writeJSON(w, http.StatusOK, s.commits(pr.GetBase().GetSHA(), pr.GetHead().GetSHA()))
+	}))
+	mux.HandleFunc("GET "+repo+"/pulls/{number}/files", s.withPR(func(w http.ResponseWriter, _ *http.Request, pr *v75github.PullRequest) {
+		writeJSON(w, http.StatusOK, s.files(pr.GetBase().GetSHA(), pr.GetHead().GetSHA()))
+	}))
+	mux.HandleFunc("GET "+repo+"/commits/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		sha := r.PathValue("sha")
+		commit := &v75github.RepositoryCommit{SHA: v75github.Ptr(sha)}
+		for _, parent := range strings.Fields(s.repo.git(s.repo.origin, "rev-list", "--parents", "-n1", sha))[1:] {
+			commit.Parents = append(commit.Parents, &v75github.Commit{SHA: v75github.Ptr(parent)})
+		}
+		commit.Files = s.files(sha+"^", sha)
+		writeJSON(w, http.StatusOK, commit)
+	})
+	mux.HandleFunc("GET "+repo+"/contents/{path...}", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	})
+	mux.HandleFunc("POST "+repo+"/pulls", func(w http.ResponseWriter, r *http.Request) {
+		var newPr v75github.NewPullRequest
+		if err := json.NewDecoder(r.Body).Decode(&newPr); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+		if !s.repo.hasBranch(newPr.GetHead()) {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "head branch does not exist"})
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		number := len(s.prs) + 1
+		pr := &v75github.PullRequest{
+			Number:  v75github.Ptr(number),
+			NodeID:  v75github.Ptr(fmt.Sprintf("PR_%d", number)),
+			State:   v75github.Ptr("open"),
+			Title:   newPr.Title,
+			Body:    newPr.Body,
+			Draft:   v75github.Ptr(newPr.GetDraft()),
+			Head:    &v75github.PullRequestBranch{Ref: newPr.Head},
+			Base:    &v75github.PullRequestBranch{Ref: newPr.Base},
+			HTMLURL: v75github.Ptr(fmt.Sprintf("https://github.com/icinga/backbot/pull/%d", number)),
+		}
+		s.prs[number] = pr
+		writeJSON(w, http.StatusCreated, pr)
+	})
+	mux.HandleFunc("POST "+repo+"/issues/{number}/comments", s.withPR(func(w http.ResponseWriter, r *http.Request, pr *v75github.PullRequest) {
+		var comment v75github.IssueComment
+		if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.comments[pr.GetNumber()] = append(s.comments[pr.GetNumber()], comment.GetBody())
+		writeJSON(w, http.StatusCreated, comment)
+	}))
+	mux.HandleFunc("POST "+repo+"/issues/{number}/labels", s.withPR(func(w http.ResponseWriter, r *http.Request, pr *v75github.PullRequest) {
+		var labels []string
+		if err := json.NewDecoder(r.Body).Decode(&labels); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.labels[pr.GetNumber()] = append(s.labels[pr.GetNumber()], labels...)
+		var ghLabels []*v75github.Label
+		for _, label := range s.labels[pr.GetNumber()] {
+			ghLabels = append(ghLabels, &v75github.Label{Name: v75github.Ptr(label)})
+		}
+		writeJSON(w, http.StatusOK, ghLabels)
+	}))
+	mux.HandleFunc("POST /api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.graphQL = append(s.graphQL, req.Variables)
+		writeJSON(w, http.StatusOK, map[string]any{"data": map[string]any{}})
+	})
+	return mux
+}
+
+// withPR wraps the given handler, looking up the pull request identified by the number path value.
+func (s *apiServer) withPR(handler func(http.ResponseWriter, *http.Request, *v75github.PullRequest)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		number, err := strconv.Atoi(r.PathValue("number"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+
+		s.mu.Lock()
+		pr, ok := s.prs[number]
+		s.mu.Unlock()
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+		handler(w, r, pr)
+	}
+}
+
+// commits returns the commits in the origin that are reachable from head but not from base, oldest first.
+func (s *apiServer) commits(base, head string) []*v75github.RepositoryCommit {
+	var commits []*v75github.RepositoryCommit
+	for _, sha := range strings.Fields(s.repo.git(s.repo.origin, "rev-list", "--reverse", base+".."+head)) {
+		commits = append(commits, &v75github.RepositoryCommit{SHA: v75github.Ptr(sha)})
+	}
+	return commits
+}
+
+// files returns the files changed between base and head in the origin, including their patches.
+func (s *apiServer) files(base, head string) []*v75github.CommitFile {
+	var files []*v75github.CommitFile
+	for _, line := range strings.Split(s.repo.git(s.repo.origin, "diff", "--numstat", base, head), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		additions, _ := strconv.Atoi(fields[0])
+		deletions, _ := strconv.Atoi(fields[1])
+		files = append(files, &v75github.CommitFile{
+			Filename:  v75github.Ptr(fields[2]),
+			Additions: v75github.Ptr(additions),
+			Deletions: v75github.Ptr(deletions),
+			Changes:   v75github.Ptr(additions + deletions),
+			Patch:     v75github.Ptr(s.repo.git(s.repo.origin, "diff", base, head, "--", fields[2])),
+		})
+	}
+	return files
+}
+
+// writeJSON writes the given value as JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,123 @@
+package backport
+
+import (
+	"context"
+	"fmt"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// existingBackport is a backport branch left over from a previous run for the same source PR and target branch,
+// e.g. when re-running the workflow or re-requesting the backport.
+type existingBackport struct {
+	pr    *v75github.PullRequest // The open backport PR of the branch, if any.
+	force bool                   // Whether the branch is recreated and must be force-pushed.
+}
+
+// forcePush returns whether the backport branch must be force-pushed. It's false for a nil existingBackport.
+func (e *existingBackport) forcePush() bool {
+	return e != nil && e.force
+}
+
+// describe returns a short description of the existing backport for the report and comments.
+func (e *existingBackport) describe(targetRef string) string {
+	if e.pr != nil {
+		return fmt.Sprintf("`%s` (#%d)", targetRef, e.pr.GetNumber())
+	}
+	return fmt.Sprintf("`%s`", targetRef)
+}
+
+// findExistingBackport looks for the given backport branch in the remote and an open PR of it into the target branch.
+//
+// Returns nil if the backport branch doesn't exist, or an error if the lookup fails.
+func (b *backPorter) findExistingBackport(ctx context.Context, targetRef, backportRef string) (*existingBackport, error) {
+	exists, err := b.git.RemoteBranchExists(ctx, backportRef)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	pr, err := b.github.FindPR(ctx, backportRef, targetRef)
+	if err != nil {
+		return nil, err
+	}
+	return &existingBackport{pr: pr}, nil
+}
+
+// prepareExistingBackport prepares backporting the given commits onto the given existing backport branch
+// according to the ExistingBackportHandling of the given inputs.
+//
+// Returns the start point to check out the backport branch from and the commits to cherry-pick onto it.
+// If the backport is not to be done, e.g. because existing backports are skipped, the result of the backport
+// is returned instead, which has already been added to the report.
+func (b *backPorter) prepareExistingBackport(ctx context.Context, cfg *Input, existing *existingBackport, targetRef, backportRef string, commitSHAs []string) (string, []string, *backportResult) {
+	startPoint := "origin/" + targetRef
+	if cfg.ExistingBackportHandling == ExistingBackportHandlingSkip {
+		githubactions.Infof("Backport branch %s already exists, skipping it as per configuration.", backportRef)
+		result := b.report.add(targetRef, outcomeSkipped, commitSHAs, fmt.Sprintf("the backport branch `%s` already exists", backportRef))
+		result.pr = existing.pr
+		return startPoint, nil, result
+	}
+
+	// Fetch the existing branch, which is also needed to force-push it with a lease.
+	if err := b.git.Fetch(ctx, fmt.Sprintf("+%[1]s:refs/remotes/origin/%[1]s", backportRef), 1); err != nil {
+		githubactions.Errorf("Failed to fetch existing backport branch %s: %v", backportRef, err)
+		return startPoint, nil, b.report.add(targetRef, outcomeFailed, commitSHAs, fmt.Sprintf("failed to fetch the existing `%s`", backportRef))
+	}
+	if cfg.ExistingBackportHandling == ExistingBackportHandlingForce {
+		githubactions.Infof("Backport branch %s already exists, recreating it as per configuration.", backportRef)
+		existing.force = true
+		return startPoint, commitSHAs, nil
+	}
+
+	githubactions.Infof("Backport branch %s already exists, appending the missing commits as per configuration.", backportRef)
+	missing, err := b.unappliedCommits(ctx, backportRef, commitSHAs)
+	if err != nil {
+		githubactions.Errorf("Failed to find commits missing on backport branch %s: %v", backportRef, err)
+		return startPoint, nil, b.report.add(targetRef, outcomeFailed, commitSHAs, fmt.Sprintf("failed to find the commits missing on `%s`", backportRef))
+	}
+	if len(missing) == 0 {
+		githubactions.Infof("Backport branch %s already contains all commits, skipping it.", backportRef)
+		result := b.report.add(targetRef, outcomeSkipped, commitSHAs, fmt.Sprintf("the backport branch `%s` is up to date", backportRef))
+		result.pr = existing.pr
+		return startPoint, nil, result
+	}
+	return "origin/" + backportRef, missing, nil
+}
+
+// openPR creates the given backport PR, unless there's an open PR of the existing backport, which is reused instead.
+//
+// The title and body of the reused PR are updated to the ones of the given PR, and a reused draft is marked ready
+// for review unless the given PR is a draft as well, e.g. because a previous conflict doesn't occur anymore. It's
+// also commented on with an update about the backport described by the given result. Returns the PR and whether
+// it's a reused one, or an error if the PR cannot be created.
+func (b *backPorter) openPR(ctx context.Context, existing *existingBackport, result *backportResult, pr *v75github.NewPullRequest) (*v75github.PullRequest, bool, error) {
+	if existing == nil || existing.pr == nil {
+		newPr, err := b.createPR(ctx, pr)
+		return newPr, false, err
+	}
+
+	githubactions.Infof("Reusing existing backport PR #%d for branch %s", existing.pr.GetNumber(), pr.GetHead())
+	b.plan.current().reused = existing.pr
+	if err := b.updatePR(ctx, existing.pr, pr); err != nil {
+		githubactions.Errorf("Failed to update title and body of PR #%d: %v", existing.pr.GetNumber(), err)
+	} else {
+		existing.pr.Title, existing.pr.Body = pr.Title, pr.Body
+	}
+	if existing.pr.GetDraft() && !pr.GetDraft() {
+		if err := b.markReadyForReview(ctx, existing.pr); err != nil {
+			githubactions.Errorf("Failed to mark PR #%d as ready for review: %v", existing.pr.GetNumber(), err)
+		} else {
+			existing.pr.Draft = v75github.Ptr(false)
+		}
+	}
+	update := "New commits were appended to the backport branch"
+	if existing.force {
+		update = "The backport branch was recreated and force-pushed"
+	}
+	body := fmt.Sprintf("🔄 %s by a re-run of the backport. %s", update, seeLogs(b.github.WorkflowRunURL()))
+	if err := b.comment(ctx, int64(existing.pr.GetNumber()), commentUpdate, result, body); err != nil {
+		githubactions.Errorf("Failed to create comment on PR #%d: %v", existing.pr.GetNumber(), err)
+	}
+	return existing.pr, true, nil
+}
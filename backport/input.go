@@ -3,7 +3,10 @@ package backport
 import (
 	"fmt"
 	"regexp"
+	"slices"
+	"strings"
 
+	v75github "github.com/google/go-github/v75/github"
 	"github.com/icinga/icinga-go-library/config"
 )
 
@@ -11,28 +14,83 @@ const (
 	MergeCommitHandlingSkip  = "skip"  // Skip merge commits when cherry-picking.
 	MergeCommitHandlingAbort = "abort" // Abort the backport if any merge commits are found.
 
-	ConflictHandlingAbort = "abort" // Abort the backport if there are conflicts.
-	ConflictHandlingDraft = "draft" // Create a draft PR if there are conflicts.
+	ConflictHandlingAbort    = "abort"    // Abort the backport if there are conflicts.
+	ConflictHandlingDraft    = "draft"    // Create a draft PR if there are conflicts.
+	ConflictHandlingMarkers  = "markers"  // Create a draft PR containing the conflict markers if there are conflicts.
+	ConflictHandlingStrategy = "strategy" // Retry conflicting commits with merge strategy options first.
+
+	ExistingBackportHandlingSkip   = "skip"   // Leave an existing backport branch and PR as they are.
+	ExistingBackportHandlingForce  = "force"  // Recreate an existing backport branch and force-push it.
+	ExistingBackportHandlingAppend = "append" // Cherry-pick the commits missing on an existing backport branch onto it.
+
+	ReviewFromAuthor    = "author"    // Request a review from the author of the source PR.
+	ReviewFromApprovers = "approvers" // Request a review from the users who approved the source PR.
+	ReviewFromMerger    = "merger"    // Request a review from the user who merged the source PR.
+
+	AutoMergeMethodMerge  = "merge"  // Auto-merge backport PRs with a merge commit.
+	AutoMergeMethodSquash = "squash" // Auto-merge backport PRs by squashing their commits.
+	AutoMergeMethodRebase = "rebase" // Auto-merge backport PRs by rebasing their commits.
 )
 
 // Input represents the inputs to the GitHub Action.
+//
+// The defaults of the optional inputs mirror the ones declared in the action.yml, so that running backbot
+// outside GitHub Actions, e.g. via its CLI, behaves the same as the action when no inputs are given.
 type Input struct {
 	// GitHubToken is the GitHub token to use for authentication.
 	//
 	// The environment variable will be unset after loading the inputs to prevent accidental exposure.
 	GitHubToken string `env:"GITHUB_TOKEN,unset"`
 
+	// AppID is the ID of the GitHub App to authenticate as instead of using the GitHubToken.
+	//
+	// If set, the PrivateKey of the app must be set as well. Backbot then authenticates as the installation of
+	// the app on the repository, both against the GitHub API and for pushing the backport branches.
+	AppID int64 `env:"APP_ID"`
+
+	// PrivateKey is the PEM encoded private key of the GitHub App identified by AppID.
+	//
+	// The environment variable will be unset after loading the inputs to prevent accidental exposure.
+	PrivateKey string `env:"PRIVATE_KEY,unset"`
+
+	// GitHubAPIURL is the URL of the GitHub REST API to use, e.g. "https://github.example.com/api/v3".
+	//
+	// Defaults to the API URL of the GitHub instance the workflow runs on, i.e. $GITHUB_API_URL.
+	GitHubAPIURL string `env:"GITHUB_API_URL"`
+
+	// GitHubServerURL is the URL of the GitHub server used for links in comments, e.g. "https://github.example.com".
+	//
+	// Defaults to the URL of the GitHub instance the workflow runs on, i.e. $GITHUB_SERVER_URL.
+	GitHubServerURL string `env:"GITHUB_SERVER_URL"`
+
 	// Committer is the name of the committer to use for git commits.
-	Committer string `env:"COMMITTER"`
+	Committer string `env:"COMMITTER" default:"github-actions[bot]"`
 
 	// Email is the email of the committer to use for git commits.
-	Email string `env:"COMMITTER_EMAIL"`
+	Email string `env:"COMMITTER_EMAIL" default:"github-actions[bot]@users.noreply.github.com"`
 
 	// Title is the title of the backport pull request.
-	Title string `env:"PR_TITLE"`
+	//
+	// Both the Title and the Description are text/template templates, in which the legacy "${...}" placeholders
+	// are supported as well, see [renderTemplate].
+	Title string `env:"PR_TITLE" default:"[Backport ${target_branch}] ${original_pr_title}"`
 
 	// Description is the description of the backport pull request.
-	Description string `env:"PR_DESCRIPTION"`
+	Description string `env:"PR_DESCRIPTION" default:"Backport of #${original_pr_number} to ${target_branch}.\n\n---\nThis is an automated backport PR. Please review it carefully before merging."`
+
+	// CommentTemplate is the text/template template all comments of backbot are rendered with.
+	//
+	// Besides the data available to the Title and Description, it has access to the Kind of the comment, i.e. one
+	// of "success", "conflict", "failure", "info" or "update", and the Message backbot comments by default.
+	// If the template renders to blank text, no comment is created. Defaults to "{{ .Message }}".
+	CommentTemplate string `env:"COMMENT_TEMPLATE" default:"{{ .Message }}"`
+
+	// BranchNameTemplate is the template of the names of the backport branches.
+	//
+	// It supports the same placeholders as the Title, as well as ${original_pr_title_slug} and ${short_merge_sha}.
+	// It must contain both the ${target_branch} and ${original_pr_number} placeholders to keep the names unique,
+	// and must result in a legal git branch name. Defaults to "backport-${original_pr_number}-to-${target_branch}".
+	BranchNameTemplate string `env:"BRANCH_NAME_TEMPLATE" default:"backport-${original_pr_number}-to-${target_branch}"`
 
 	// CopyLabelsPattern is a regex pattern to match labels that should be copied from the original pull request
 	// to the backport pull request. If not set, none are copied.
@@ -44,20 +102,59 @@ type Input struct {
 	// LabelPattern is a regex pattern to match labels that should be used to determine target branches for backporting.
 	//
 	// The part of the label that matches the first capturing group will be used as the target branch name.
-	// For example, if you set this to `backport-to-(support\/\d+\.\d+)`, and the original pull request has
+	// For example, if you set this to `^backport-to-(support\/\d+\.\d+)$`, and the original pull request has
 	// a label `backport-to-support/2.15`, a backport will be created to the `support/2.15` branch.
 	//
-	// By default, this is set to `backport-to-(support\/\d+\.\d+)`.
-	LabelPattern string `env:"LABEL_PATTERN" default:"backport-to-(support\\/\\d+\\.\\d+)"`
+	// By default, this is set to `^backport-to-(support\/\d+\.\d+)$`.
+	LabelPattern string `env:"LABEL_PATTERN" default:"^backport-to-(support\\/\\d+\\.\\d+)$"`
 
 	// labelRegex is the compiled regex from LabelPattern. This is not set from environment variables.
 	labelRegex *regexp.Regexp `env:"-"`
 
+	// SupportedBranchesPattern is a regex pattern matching the supported branches, which the AllSupportedLabel
+	// and the labels matching the SinceLabelPattern expand to.
+	//
+	// The version of a branch is the part of its name that matches the first capturing group, if any, or the
+	// last version number in its name otherwise, e.g. "2.14" for "support/2.14".
+	// By default, this is set to `^support\/\d+\.\d+$`.
+	SupportedBranchesPattern string `env:"SUPPORTED_BRANCHES_PATTERN" default:"^support\\/\\d+\\.\\d+$"`
+
+	// supportedBranchesRegex is the compiled regex from SupportedBranchesPattern. Not set from environment variables.
+	supportedBranchesRegex *regexp.Regexp `env:"-"`
+
+	// AllSupportedLabel is the label that requests a backport to all the supported branches.
+	//
+	// By default, this is set to "backport-to-all-supported". Set it to an empty string to disable it.
+	AllSupportedLabel string `env:"ALL_SUPPORTED_LABEL" default:"backport-to-all-supported"`
+
+	// SinceLabelPattern is a regex pattern to match labels that request a backport to the supported branches
+	// with a version greater than or equal to the one matching its first capturing group.
+	//
+	// For example, the label `backport-since-2.13` requests backports to `support/2.13`, `support/2.14` and so on.
+	// By default, this is set to `^backport-since-(\d+(?:\.\d+)*)$`. Set it to an empty string to disable it.
+	SinceLabelPattern string `env:"SINCE_LABEL_PATTERN" default:"^backport-since-(\\d+(?:\\.\\d+)*)$"`
+
+	// sinceLabelRegex is the compiled regex from SinceLabelPattern. This is not set from environment variables.
+	sinceLabelRegex *regexp.Regexp `env:"-"`
+
 	// ConflictHandling determines how to handle conflicts during cherry-picking.
 	//
-	// You can set this to "abort" to abort the backport if there are conflicts, or "draft" to create
-	// a draft pull request that needs to be resolved manually. Defaults to "abort".
-	ConflictHandling string `env:"CONFLICT_HANDLING"`
+	// You can set this to "abort" to abort the backport if there are conflicts, or "draft" to create a draft pull
+	// request that needs to be resolved manually. "markers" creates a draft pull request as well, but commits the
+	// conflicting files including their conflict markers, so that they can be resolved right in the pull request.
+	// With "strategy", conflicting commits are retried with each of the StrategyOptions first, and the
+	// StrategyFallback applies if all of them fail as well. Defaults to "abort".
+	ConflictHandling string `env:"CONFLICT_HANDLING" default:"abort"`
+
+	// StrategyOptions are the merge strategy options to retry conflicting commits with, in the given order.
+	//
+	// Each option is passed to `git cherry-pick --strategy-option`, e.g. "ignore-space-change",
+	// "diff-algorithm=histogram" or "theirs". Only used with the "strategy" conflict handling.
+	StrategyOptions []string `env:"STRATEGY_OPTIONS"`
+
+	// StrategyFallback is the conflict handling to fall back to if none of the StrategyOptions resolves
+	// a conflict. Can be "abort", "draft" or "markers". Defaults to "abort".
+	StrategyFallback string `env:"STRATEGY_FALLBACK" default:"abort"`
 
 	// MergeCommitHandling determines whether to skip merge commits when cherry-picking from the source pull request.
 	//
@@ -65,12 +162,302 @@ type Input struct {
 	// due to merges from some other pull requests, and you want to either include, skip them or fail the backport
 	// if any are found. Can be "skip", or "abort" and any other value is treated as "include". Defaults to "skip".
 	MergeCommitHandling string `env:"MERGE_COMMIT_HANDLING" default:"skip"`
+
+	// ExistingBackportHandling determines what to do if the backport branch already exists, e.g. when re-running
+	// a workflow or re-requesting a backport.
+	//
+	// You can set this to "skip" to leave the existing branch and its open pull request as they are, "force" to
+	// recreate the branch from the target branch and force-push it, or "append" to cherry-pick only the commits
+	// that are missing on the existing branch onto it. An open backport pull request of the branch is reused
+	// rather than creating another one. Defaults to "skip".
+	ExistingBackportHandling string `env:"EXISTING_BACKPORT_HANDLING" default:"skip"`
+
+	// Reviewers is a list of users to request a review from on the backport pull requests.
+	//
+	// Teams can be specified in the form of "org/team-slug". Defaults to none.
+	Reviewers []string `env:"REVIEWERS"`
+
+	// ReviewFrom lists whom of the users involved in the source pull request to request a review from on the
+	// backport pull requests, in addition to the Reviewers.
+	//
+	// Can contain "author" for the author of the source pull request, "approvers" for the users who approved it,
+	// and "merger" for the user who merged it. Defaults to none.
+	ReviewFrom []string `env:"REVIEW_FROM"`
+
+	// AssignAuthor enables assigning the author of the source pull request to the backport pull requests.
+	// Defaults to false.
+	AssignAuthor bool `env:"ASSIGN_AUTHOR"`
+
+	// CopyAssignees enables assigning the assignees of the source pull request to the backport pull requests.
+	// Defaults to false.
+	CopyAssignees bool `env:"COPY_ASSIGNEES"`
+
+	// MilestoneTemplate is the template for the title of the milestone to set on the backport pull requests.
+	//
+	// It's rendered like the Title, so that e.g. "v${target_version}" sets the milestone "v2.14" on backports to
+	// "support/2.14". The milestone must already exist and be open. Defaults to none, i.e. no milestone is set.
+	MilestoneTemplate string `env:"MILESTONE_TEMPLATE"`
+
+	// CopyProjects enables adding the backport pull requests to the same projects as the source pull request.
+	//
+	// This requires a token that is allowed to read and write the projects, which the GITHUB_TOKEN of workflows
+	// usually isn't. Defaults to false.
+	CopyProjects bool `env:"COPY_PROJECTS"`
+
+	// AutoMerge enables GitHub's auto-merge on the backport pull requests, so that they are merged with the
+	// AutoMergeMethod once all their requirements, such as passing status checks, are met.
+	//
+	// Auto-merge is never enabled on draft pull requests, e.g. the ones created due to conflicts, nor on pull requests
+	// whose conflicts were resolved with StrategyOptions, which need a human review. It must be allowed in the
+	// repository settings. Defaults to false.
+	AutoMerge bool `env:"AUTO_MERGE"`
+
+	// AutoMergeMethod is the merge method used to auto-merge the backport pull requests.
+	//
+	// Can be either "merge", "squash" or "rebase". Defaults to "merge".
+	AutoMergeMethod string `env:"AUTO_MERGE_METHOD" default:"merge"`
+
+	// Rerere enables git's "reuse recorded resolution" feature for cherry-picking.
+	//
+	// Recorded resolutions are restored from the RerereRef before cherry-picking, so that conflicts which have
+	// been resolved before are resolved automatically. Once a backport PR with conflicts is merged after they
+	// were resolved by hand, the resolutions are recorded and pushed to the RerereRef. Defaults to false.
+	Rerere bool `env:"RERERE"`
+
+	// RerereRef is the ref in the repository that the recorded resolutions are shared with across runs.
+	//
+	// Defaults to "refs/backbot/rerere", which isn't a branch, so it doesn't show up in the GitHub UI.
+	RerereRef string `env:"RERERE_REF" default:"refs/backbot/rerere"`
+
+	// DryRun enables the dry-run mode, in which backbot plans the backports without pushing or commenting anything.
+	//
+	// The target branches, merge kind and commits are determined as usual, and the commits are cherry-picked
+	// locally, but the resulting plan is only written to the job summary instead of being applied. Defaults to false.
+	DryRun bool `env:"DRY_RUN"`
+
+	// ConfigFile is the path of the config file within the repository, which is read from the default branch.
+	//
+	// The settings in that file take precedence over the action inputs. If the file doesn't exist, only the
+	// action inputs are used. Set it to an empty string to disable loading the config file entirely.
+	// Defaults to ".github/backbot.yml".
+	ConfigFile string `env:"CONFIG_FILE" default:".github/backbot.yml"`
+
+	// branchAliases maps branch names captured by the LabelPattern to the actual target branches, as defined in the
+	// config file. Not set from environment variables.
+	branchAliases map[string]string `env:"-"`
+
+	// branchPolicies holds the per target branch settings from the config file. Not set from environment variables.
+	branchPolicies map[string]Policy `env:"-"`
+
+	// branchInputs holds the validated inputs with the branchPolicies applied. Not set from environment variables.
+	branchInputs map[string]*Input `env:"-"`
+
+	// rules holds the rules from the config file, validated along with the inputs. Not set from environment variables.
+	rules []*labelRule `env:"-"`
+}
+
+// labelRule is a [Rule] of the config file along with the inputs resulting from applying its settings.
+type labelRule struct {
+	Rule
+
+	labelRegex   *regexp.Regexp    // The compiled regex from the LabelPattern of the rule.
+	input        *Input            // The validated inputs with the settings of the rule applied.
+	branchInputs map[string]*Input // The validated inputs with the settings of a branch and then the rule applied.
+}
+
+// ApplyRepoConfig merges the given repository config into the inputs.
+//
+// Only the settings that are set in the config file override the inputs. The per branch settings are not
+// applied immediately but are used by [Input.ForBranch] after the inputs have been validated again.
+func (in *Input) ApplyRepoConfig(cfg *RepoConfig) {
+	if cfg.LabelPattern != nil {
+		in.LabelPattern = *cfg.LabelPattern
+	}
+	if cfg.MergeCommitHandling != nil {
+		in.MergeCommitHandling = *cfg.MergeCommitHandling
+	}
+	if cfg.SupportedBranchesPattern != nil {
+		in.SupportedBranchesPattern = *cfg.SupportedBranchesPattern
+	}
+	if cfg.AllSupportedLabel != nil {
+		in.AllSupportedLabel = *cfg.AllSupportedLabel
+	}
+	if cfg.SinceLabelPattern != nil {
+		in.SinceLabelPattern = *cfg.SinceLabelPattern
+	}
+	in.applyPolicy(cfg.Policy)
+	in.branchPolicies = cfg.Branches
+	in.branchAliases = cfg.BranchAliases
+	in.rules = nil
+	for _, rule := range cfg.Rules {
+		in.rules = append(in.rules, &labelRule{Rule: rule})
+	}
+}
+
+// requestsBackport returns whether the given label requests backports, i.e. whether it's used to determine the
+// target branches rather than being a regular label, e.g. one to copy to the backport PRs.
+func (in *Input) requestsBackport(label string) bool {
+	if _, ok := in.supportedSince(label); ok {
+		return true
+	}
+	if in.labelRegex != nil && in.labelRegex.MatchString(label) {
+		return true
+	}
+	return slices.ContainsFunc(in.rules, func(rule *labelRule) bool {
+		return rule.labelRegex != nil && rule.labelRegex.MatchString(label)
+	})
+}
+
+// forTarget returns the inputs to use for backports to the given target.
+//
+// If the target was requested by a label of a rule, the inputs of the rule are returned, which include the
+// settings of the target branch. Otherwise, this is the same as [Input.ForBranch].
+func (in *Input) forTarget(t target) *Input {
+	if t.rule == nil {
+		return in.ForBranch(t.ref)
+	}
+	if branchIn, ok := t.rule.branchInputs[t.ref]; ok {
+		return branchIn
+	}
+	return t.rule.input
+}
+
+// ForBranch returns the inputs to use for backports to the given target branch.
+//
+// If the config file defines specific settings for that branch, a copy of the inputs with these settings
+// applied is returned. Otherwise, the inputs are returned as is.
+func (in *Input) ForBranch(branch string) *Input {
+	if branchIn, ok := in.branchInputs[branch]; ok {
+		return branchIn
+	}
+	return in
+}
+
+// applyPolicy overrides the inputs with all the settings that are set in the given policy.
+func (in *Input) applyPolicy(p Policy) {
+	if p.Title != nil {
+		in.Title = *p.Title
+	}
+	if p.Description != nil {
+		in.Description = *p.Description
+	}
+	if p.CopyLabelsPattern != nil {
+		in.CopyLabelsPattern = *p.CopyLabelsPattern
+	}
+	if p.ConflictHandling != nil {
+		in.ConflictHandling = *p.ConflictHandling
+	}
+	if p.StrategyOptions != nil {
+		in.StrategyOptions = p.StrategyOptions
+	}
+	if p.StrategyFallback != nil {
+		in.StrategyFallback = *p.StrategyFallback
+	}
+	if p.Reviewers != nil {
+		in.Reviewers = p.Reviewers
+	}
+	if p.ReviewFrom != nil {
+		in.ReviewFrom = p.ReviewFrom
+	}
+	if p.AssignAuthor != nil {
+		in.AssignAuthor = *p.AssignAuthor
+	}
+	if p.CopyAssignees != nil {
+		in.CopyAssignees = *p.CopyAssignees
+	}
+	if p.MilestoneTemplate != nil {
+		in.MilestoneTemplate = *p.MilestoneTemplate
+	}
+	if p.CopyProjects != nil {
+		in.CopyProjects = *p.CopyProjects
+	}
+	if p.AutoMerge != nil {
+		in.AutoMerge = *p.AutoMerge
+	}
+	if p.AutoMergeMethod != nil {
+		in.AutoMergeMethod = *p.AutoMergeMethod
+	}
 }
 
 // Validate checks that the required fields are set and valid.
+//
+// This also validates the branch aliases and the inputs resulting from applying the per branch settings of the
+// config file.
 func (in *Input) Validate() error {
-	if in.GitHubToken == "" {
-		return fmt.Errorf("github_token is required")
+	if err := in.validate(); err != nil {
+		return err
+	}
+	for alias, branch := range in.branchAliases {
+		if err := validateBranchName(branch); err != nil {
+			return fmt.Errorf("invalid branch of branch alias %s: %w", alias, err)
+		}
+	}
+
+	in.branchInputs = make(map[string]*Input, len(in.branchPolicies))
+	for branch, policy := range in.branchPolicies {
+		branchIn := *in
+		branchIn.branchPolicies, branchIn.branchInputs = nil, nil
+		branchIn.applyPolicy(policy)
+		if err := branchIn.validate(); err != nil {
+			return fmt.Errorf("branch %s: %w", branch, err)
+		}
+		in.branchInputs[branch] = &branchIn
+	}
+
+	for i, rule := range in.rules {
+		if err := in.validateRule(rule); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateRule compiles the label pattern of the given rule and validates the inputs resulting from applying its
+// settings on top of the inputs and the per branch settings of the config file.
+func (in *Input) validateRule(rule *labelRule) error {
+	if rule.LabelPattern == "" {
+		return fmt.Errorf("label_pattern is required")
+	}
+	re, err := regexp.Compile(rule.LabelPattern)
+	if err != nil {
+		return fmt.Errorf("failed to compile label_pattern regex: %w", err)
+	}
+	if re.NumSubexp() == 0 {
+		return fmt.Errorf("label_pattern must have a capturing group for the target branch")
+	}
+	rule.labelRegex = re
+
+	ruleIn := *in
+	ruleIn.branchPolicies, ruleIn.branchInputs = nil, nil
+	ruleIn.applyPolicy(rule.Policy)
+	if err := ruleIn.validate(); err != nil {
+		return err
+	}
+	rule.input = &ruleIn
+
+	rule.branchInputs = make(map[string]*Input, len(in.branchPolicies))
+	for branch, policy := range in.branchPolicies {
+		branchIn := *in
+		branchIn.branchPolicies, branchIn.branchInputs = nil, nil
+		branchIn.applyPolicy(policy)
+		branchIn.applyPolicy(rule.Policy)
+		if err := branchIn.validate(); err != nil {
+			return fmt.Errorf("branch %s: %w", branch, err)
+		}
+		rule.branchInputs[branch] = &branchIn
+	}
+	return nil
+}
+
+// validate checks that the required fields are set and valid, ignoring the per branch settings.
+func (in *Input) validate() error {
+	switch {
+	case in.AppID != 0 || in.PrivateKey != "":
+		if in.AppID == 0 || in.PrivateKey == "" {
+			return fmt.Errorf("app_id and private_key must be given together")
+		}
+	case in.GitHubToken == "":
+		return fmt.Errorf("github_token is required unless app_id and private_key are given")
 	}
 	if in.Committer == "" {
 		return fmt.Errorf("committer is required")
@@ -84,6 +471,36 @@ func (in *Input) Validate() error {
 	if in.Description == "" {
 		return fmt.Errorf("pr_description is required")
 	}
+	for name, text := range map[string]string{
+		"pr_title":           in.Title,
+		"pr_description":     in.Description,
+		"comment_template":   in.CommentTemplate,
+		"milestone_template": in.MilestoneTemplate,
+	} {
+		if _, err := parseTemplate(name, text); err != nil {
+			return err
+		}
+	}
+	for _, placeholder := range []string{"${target_branch}", "${original_pr_number}"} {
+		if !strings.Contains(in.BranchNameTemplate, placeholder) {
+			return fmt.Errorf("branch_name_template must contain the %s placeholder", placeholder)
+		}
+	}
+	// The title and description may contain anything, which mostly results in illegal branch names.
+	for _, placeholder := range []string{"${original_pr_title}", "${original_pr_description}"} {
+		if strings.Contains(in.BranchNameTemplate, placeholder) {
+			return fmt.Errorf("branch_name_template must not contain the %s placeholder, use ${original_pr_title_slug} instead", placeholder)
+		}
+	}
+	sample := makeBackportBranchName(in.BranchNameTemplate, "support/1.0", &v75github.PullRequest{
+		Number:         v75github.Ptr(1),
+		Title:          v75github.Ptr("Title"),
+		MergeCommitSHA: v75github.Ptr("0123456789abcdef0123456789abcdef01234567"),
+	})
+	if err := validateBranchName(sample); err != nil {
+		return fmt.Errorf("invalid branch_name_template: %w", err)
+	}
+	in.copyLabelRegex = nil
 	if in.CopyLabelsPattern != "" {
 		re, err := regexp.Compile(in.CopyLabelsPattern)
 		if err != nil {
@@ -99,13 +516,90 @@ func (in *Input) Validate() error {
 		return fmt.Errorf("failed to compile label_pattern regex: %w", err)
 	}
 	in.labelRegex = re
+	if in.SupportedBranchesPattern == "" {
+		return fmt.Errorf("supported_branches_pattern is required")
+	}
+	if in.supportedBranchesRegex, err = regexp.Compile(in.SupportedBranchesPattern); err != nil {
+		return fmt.Errorf("failed to compile supported_branches_pattern regex: %w", err)
+	}
+	in.sinceLabelRegex = nil
+	if in.SinceLabelPattern != "" {
+		if in.sinceLabelRegex, err = regexp.Compile(in.SinceLabelPattern); err != nil {
+			return fmt.Errorf("failed to compile since_label_pattern regex: %w", err)
+		}
+		if in.sinceLabelRegex.NumSubexp() == 0 {
+			return fmt.Errorf("since_label_pattern must have a capturing group for the version")
+		}
+	}
 
-	if in.ConflictHandling != "abort" && in.ConflictHandling != "draft" {
-		return fmt.Errorf("expected input 'conflict_handling' to be either 'abort' or 'draft', got: '%s'", in.ConflictHandling)
+	switch in.ConflictHandling {
+	case ConflictHandlingAbort, ConflictHandlingDraft, ConflictHandlingMarkers:
+	case ConflictHandlingStrategy:
+		var options []string
+		for _, option := range in.StrategyOptions {
+			if option = strings.TrimSpace(option); option != "" {
+				options = append(options, option)
+			}
+		}
+		if len(options) == 0 {
+			return fmt.Errorf("strategy_options is required for conflict_handling 'strategy'")
+		}
+		in.StrategyOptions = options
+		switch in.StrategyFallback {
+		case ConflictHandlingAbort, ConflictHandlingDraft, ConflictHandlingMarkers:
+		default:
+			return fmt.Errorf(
+				"expected input 'strategy_fallback' to be either 'abort', 'draft' or 'markers', got: '%s'",
+				in.StrategyFallback,
+			)
+		}
+	default:
+		return fmt.Errorf(
+			"expected input 'conflict_handling' to be either 'abort', 'draft', 'markers' or 'strategy', got: '%s'",
+			in.ConflictHandling,
+		)
 	}
 	if in.MergeCommitHandling == "" {
 		return fmt.Errorf("merge_commit_handling is required")
 	}
+	switch in.ExistingBackportHandling {
+	case ExistingBackportHandlingSkip, ExistingBackportHandlingForce, ExistingBackportHandlingAppend:
+	default:
+		return fmt.Errorf(
+			"expected input 'existing_backport_handling' to be either 'skip', 'force' or 'append', got: '%s'",
+			in.ExistingBackportHandling,
+		)
+	}
+	var reviewers []string
+	for _, reviewer := range in.Reviewers {
+		if reviewer = strings.TrimSpace(reviewer); reviewer != "" {
+			reviewers = append(reviewers, reviewer)
+		}
+	}
+	in.Reviewers = reviewers
+	var reviewFrom []string
+	for _, from := range in.ReviewFrom {
+		switch from = strings.TrimSpace(from); from {
+		case "":
+		case ReviewFromAuthor, ReviewFromApprovers, ReviewFromMerger:
+			reviewFrom = append(reviewFrom, from)
+		default:
+			return fmt.Errorf("expected input 'review_from' to contain only 'author', 'approvers' or 'merger', got: '%s'", from)
+		}
+	}
+	in.ReviewFrom = reviewFrom
+	switch in.AutoMergeMethod {
+	case AutoMergeMethodMerge, AutoMergeMethodSquash, AutoMergeMethodRebase:
+	default:
+		return fmt.Errorf(
+			"expected input 'auto_merge_method' to be either 'merge', 'squash' or 'rebase', got: '%s'",
+			in.AutoMergeMethod,
+		)
+	}
+
+	if in.Rerere && !strings.HasPrefix(in.RerereRef, "refs/") {
+		return fmt.Errorf("expected input 'rerere_ref' to be a full ref starting with 'refs/', got: '%s'", in.RerereRef)
+	}
 	return nil
 }
 
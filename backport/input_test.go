@@ -32,4 +32,48 @@ func TestInput(t *testing.T) {
 	require.Equal(t, "label-pattern", input.LabelPattern)
 	require.Equal(t, "abort", input.ConflictHandling)
 	require.Equal(t, "skip", input.MergeCommitHandling)
+	require.Equal(t, "skip", input.ExistingBackportHandling)
+	require.Equal(t, "backport-${original_pr_number}-to-${target_branch}", input.BranchNameTemplate)
+	require.Equal(t, "{{ .Message }}", input.CommentTemplate)
+	require.Equal(t, "merge", input.AutoMergeMethod)
+	require.Equal(t, `^support\/\d+\.\d+$`, input.SupportedBranchesPattern)
+	require.Equal(t, "backport-to-all-supported", input.AllSupportedLabel)
+	require.Equal(t, `^backport-since-(\d+(?:\.\d+)*)$`, input.SinceLabelPattern)
+}
+
+func TestInputDefaults(t *testing.T) {
+	t.Setenv("INPUT_GITHUB_TOKEN", "token")
+
+	input, err := LoadInputsFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "github-actions[bot]", input.Committer)
+	require.Equal(t, `^backport-to-(support\/\d+\.\d+)$`, input.LabelPattern)
+	require.Equal(t, "abort", input.ConflictHandling)
+	require.NoError(t, input.Validate())
+}
+
+func TestBranchNameTemplate(t *testing.T) {
+	t.Setenv("INPUT_GITHUB_TOKEN", "token")
+	t.Setenv("INPUT_COMMITTER", "committer")
+	t.Setenv("INPUT_COMMITTER_EMAIL", "email")
+	t.Setenv("INPUT_PR_TITLE", "title")
+	t.Setenv("INPUT_PR_DESCRIPTION", "description")
+	t.Setenv("INPUT_LABEL_PATTERN", `^backport-to-(\S+)$`)
+	t.Setenv("INPUT_CONFLICT_HANDLING", "abort")
+
+	input, err := LoadInputsFromEnv()
+	require.NoError(t, err)
+	require.NoError(t, input.Validate())
+
+	input.BranchNameTemplate = "backport/${target_branch}/${original_pr_number}-${original_pr_title_slug}"
+	require.NoError(t, input.Validate())
+
+	input.BranchNameTemplate = "backport/${target_branch}"
+	require.EqualError(t, input.Validate(), "branch_name_template must contain the ${original_pr_number} placeholder")
+
+	input.BranchNameTemplate = "backport/${target_branch}/${original_pr_number}-${original_pr_title}"
+	require.ErrorContains(t, input.Validate(), "must not contain the ${original_pr_title} placeholder")
+
+	input.BranchNameTemplate = "backport/${target_branch}/${original_pr_number}-${original_pr_description}"
+	require.ErrorContains(t, input.Validate(), "must not contain the ${original_pr_description} placeholder")
 }
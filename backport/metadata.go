@@ -0,0 +1,54 @@
+package backport
+
+import (
+	"context"
+	"strings"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/yhabteab/backbot/github"
+)
+
+// milestoneOf returns the milestone to set on the backport PR described by the given result, as per the
+// MilestoneTemplate of the given inputs.
+//
+// Returns nil if no milestone is to be set. Failing to render the template or to find the milestone is not fatal,
+// it's logged, and nil is returned as well.
+func (b *backPorter) milestoneOf(ctx context.Context, cfg *Input, sourcePr *v75github.PullRequest, result *backportResult) *v75github.Milestone {
+	if cfg.MilestoneTemplate == "" {
+		return nil
+	}
+	title, err := renderTemplate("milestone", cfg.MilestoneTemplate, newTemplateData(sourcePr, result))
+	if err != nil {
+		githubactions.Warningf("Failed to render the milestone of the backport to %s: %v", result.target, err)
+		return nil
+	}
+	if title = strings.TrimSpace(title); title == "" {
+		return nil
+	}
+
+	milestone, err := b.github.FindMilestone(ctx, title)
+	if err != nil {
+		githubactions.Warningf("Failed to look up milestone %q: %v", title, err)
+		return nil
+	}
+	if milestone == nil {
+		githubactions.Warningf("Milestone %q doesn't exist or is closed, not setting it on the backport to %s", title, result.target)
+	}
+	return milestone
+}
+
+// projectsOf returns the projects the given source PR has been added to, if the given inputs ask to copy them.
+//
+// Failing to retrieve the projects is not fatal, it's logged, and no projects are returned.
+func (b *backPorter) projectsOf(ctx context.Context, cfg *Input, sourcePr *v75github.PullRequest) []github.Project {
+	if !cfg.CopyProjects {
+		return nil
+	}
+	projects, err := b.github.GetProjects(ctx, sourcePr)
+	if err != nil {
+		githubactions.Warningf("Failed to retrieve the projects of PR #%d: %v", sourcePr.GetNumber(), err)
+		return nil
+	}
+	return projects
+}
@@ -0,0 +1,154 @@
+package backport
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/yhabteab/backbot/git"
+	"github.com/yhabteab/backbot/github"
+)
+
+// outcome describes how the backport to a single target branch ended.
+type outcome string
+
+const (
+	outcomeCreated    = outcome("created")             // The backport PR was created.
+	outcomeUpdated    = outcome("updated")             // The existing backport PR of a previous run was updated.
+	outcomeDraft      = outcome("draft with conflict") // A draft backport PR was created due to a conflict.
+	outcomeAborted    = outcome("aborted")             // The backport was aborted due to a conflict or configuration.
+	outcomeSkipped    = outcome("skipped")             // The backport was not attempted, e.g. the branch doesn't exist.
+	outcomeBackported = outcome("already backported")  // All commits are already present on the target branch.
+	outcomeFailed     = outcome("failed")              // The backport failed due to an error, e.g. pushing failed.
+)
+
+// backportResult is the result of backporting the source PR to a single target branch.
+type backportResult struct {
+	target          string                 // The target branch of the backport.
+	branch          string                 // The backport branch, if any.
+	outcome         outcome                // How the backport ended.
+	pr              *v75github.PullRequest // The created or updated backport PR, if any.
+	commitSHAs      []string               // The commits cherry-picked onto the target branch.
+	conflict        bool                   // Whether cherry-picking the commits conflicts.
+	resolved        []resolvedConflict     // The conflicts resolved automatically with merge strategy options.
+	pending         []string               // The commits not cleanly cherry-picked due to a conflict, if any.
+	conflictedFiles []git.ConflictedFile   // The files that conflict, if any.
+	markers         bool                   // Whether the conflicting files were committed including their conflict markers.
+	autoMerge       bool                   // Whether auto-merge was enabled on the backport PR.
+	details         string                 // Why the backport was aborted, skipped or failed, if so.
+}
+
+// report collects the results of a backport run, which is written to the GitHub Actions job summary.
+type report struct {
+	sourcePr  *v75github.PullRequest
+	mergeKind github.MergeKind
+	results   []*backportResult
+}
+
+// add records the result of backporting to the given target branch.
+//
+// Returns the recorded result.
+func (r *report) add(target string, oc outcome, commitSHAs []string, details string) *backportResult {
+	result := &backportResult{target: target, outcome: oc, commitSHAs: commitSHAs, details: details}
+	r.results = append(r.results, result)
+	return result
+}
+
+// String renders the report as a Markdown document suitable for the GitHub Actions job summary.
+func (r *report) String() string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "## Backport of #%d: %s\n\n", r.sourcePr.GetNumber(), r.sourcePr.GetTitle())
+	if r.mergeKind != github.MergeInvalid {
+		_, _ = fmt.Fprintf(&sb, "Merge kind: **%s**\n\n", r.mergeKind)
+	}
+	if len(r.results) == 0 {
+		sb.WriteString("No backports were attempted.\n\n")
+		return sb.String()
+	}
+
+	sb.WriteString("| Target branch | Outcome | Pull request | Commits | Details |\n")
+	sb.WriteString("|---------------|---------|--------------|---------|---------|\n")
+	for _, result := range r.results {
+		pr := "-"
+		if result.pr.GetNumber() != 0 {
+			pr = fmt.Sprintf("[#%d](%s)", result.pr.GetNumber(), result.pr.GetHTMLURL())
+		}
+		commits := "-"
+		if len(result.commitSHAs) != 0 {
+			var shortSHAs []string
+			for _, sha := range result.commitSHAs {
+				shortSHAs = append(shortSHAs, fmt.Sprintf("`%.7s`", sha))
+			}
+			commits = strings.Join(shortSHAs, ", ")
+		}
+		details := "-"
+		if result.details != "" {
+			details = strings.ReplaceAll(result.details, "|", `\|`)
+		}
+		_, _ = fmt.Fprintf(&sb, "| `%s` | %s | %s | %s | %s |\n", result.target, result.outcome, pr, commits, details)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// createdPullRequest describes a created or updated backport PR in the created_pull_requests output.
+type createdPullRequest struct {
+	Number    int    `json:"number"`
+	URL       string `json:"url"`
+	Base      string `json:"base"`
+	Draft     bool   `json:"draft"`
+	AutoMerge bool   `json:"auto_merge"`
+}
+
+// outputs returns the step outputs of the GitHub Action summarizing the report, keyed by their names.
+//
+// All lists are encoded as JSON arrays, which are empty rather than null if there are no elements,
+// so that they can be consumed via the fromJSON() expression of GitHub Actions. The PRs a dry run would
+// have created don't have a number yet, so they are left out of the created_pull_requests.
+func (r *report) outputs() (map[string]string, error) {
+	created := []createdPullRequest{}
+	failed, conflicting := []string{}, []string{}
+	for _, result := range r.results {
+		if result.pr.GetNumber() != 0 && (result.outcome == outcomeCreated || result.outcome == outcomeUpdated || result.outcome == outcomeDraft) {
+			created = append(created, createdPullRequest{
+				Number:    result.pr.GetNumber(),
+				URL:       result.pr.GetHTMLURL(),
+				Base:      result.target,
+				Draft:     result.outcome == outcomeDraft,
+				AutoMerge: result.autoMerge,
+			})
+		}
+		if result.outcome == outcomeAborted || result.outcome == outcomeFailed {
+			failed = append(failed, result.target)
+		}
+		if result.conflict {
+			conflicting = append(conflicting, result.target)
+		}
+	}
+
+	outputs := make(map[string]string)
+	for name, value := range map[string]any{
+		"created_pull_requests": created,
+		"failed_targets":        failed,
+		"conflicting_targets":   conflicting,
+	} {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode output %s: %w", name, err)
+		}
+		outputs[name] = string(encoded)
+	}
+
+	switch r.mergeKind {
+	case github.Squash:
+		outputs["merge_kind"] = "squash"
+	case github.MergeCommit:
+		outputs["merge_kind"] = "merge"
+	case github.Rebase:
+		outputs["merge_kind"] = "rebase"
+	default:
+		outputs["merge_kind"] = ""
+	}
+	return outputs, nil
+}
@@ -0,0 +1,45 @@
+package backport
+
+import (
+	"testing"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/stretchr/testify/require"
+	"github.com/yhabteab/backbot/github"
+)
+
+func TestReport(t *testing.T) {
+	r := &report{
+		sourcePr: &v75github.PullRequest{Number: v75github.Ptr(1), Title: v75github.Ptr("Add feature")},
+	}
+	require.Equal(t, "## Backport of #1: Add feature\n\nNo backports were attempted.\n\n", r.String())
+
+	r.mergeKind = github.Rebase
+	created := r.add("support/1.0", outcomeCreated, []string{"0123456789abcdef", "fedcba9876543210"}, "")
+	created.pr = &v75github.PullRequest{
+		Number:  v75github.Ptr(2),
+		HTMLURL: v75github.Ptr("https://github.com/icinga/backbot/pull/2"),
+	}
+	created.autoMerge = true
+	r.add("support/1.1", outcomeSkipped, nil, "the branch does not exist")
+	r.add("support/1.2", outcomeAborted, []string{"0123456789abcdef"}, "a | b")
+
+	require.Equal(t, `## Backport of #1: Add feature
+
+Merge kind: **Rebase and Merge**
+
+| Target branch | Outcome | Pull request | Commits | Details |
+|---------------|---------|--------------|---------|---------|
+| `+"`support/1.0` | created | [#2](https://github.com/icinga/backbot/pull/2) | `0123456`, `fedcba9` | - |\n"+
+		"| `support/1.1` | skipped | - | - | the branch does not exist |\n"+
+		"| `support/1.2` | aborted | - | `0123456` | a \\| b |\n\n", r.String())
+
+	outputs, err := r.outputs()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"created_pull_requests": `[{"number":2,"url":"https://github.com/icinga/backbot/pull/2","base":"support/1.0","draft":false,"auto_merge":true}]`,
+		"failed_targets":        `["support/1.2"]`,
+		"conflicting_targets":   `[]`,
+		"merge_kind":            "rebase",
+	}, outputs)
+}
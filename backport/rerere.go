@@ -0,0 +1,92 @@
+package backport
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/yhabteab/backbot/github"
+)
+
+// setUpRerere enables rerere and restores the recorded resolutions shared via the RerereRef.
+//
+// If record is true and the given merged PR is a backport PR created by backbot, how its conflicts were resolved
+// is recorded and pushed to the RerereRef as well, so that subsequent backports of the same changes reuse the
+// resolutions. Failing to share the resolutions is not fatal, only enabling rerere is.
+func (b *backPorter) setUpRerere(ctx context.Context, pr *v75github.PullRequest, record bool) error {
+	if err := b.git.EnableRerere(ctx); err != nil {
+		return err
+	}
+	if err := b.git.RestoreRerere(ctx, b.config.RerereRef); err != nil {
+		githubactions.Warningf("Failed to restore recorded resolutions from %s: %v", b.config.RerereRef, err)
+	}
+	if !record {
+		return nil
+	}
+	if err := b.recordResolutions(ctx, pr); err != nil {
+		githubactions.Warningf("Failed to record the conflict resolutions of PR #%d: %v", pr.GetNumber(), err)
+	}
+	return nil
+}
+
+// recordResolutions records the conflict resolutions of the given merged backport PR and pushes them to the RerereRef.
+//
+// The commits of the source PR are cherry-picked onto the base of the backport branch once more, with a detached
+// HEAD, and their conflicts are resolved as in the head of the backport PR. The previously checked out branch or
+// commit is checked out again afterward. Does nothing if the PR isn't a backport PR.
+func (b *backPorter) recordResolutions(ctx context.Context, backportPr *v75github.PullRequest) error {
+	srcPrNumber, ok := matchBackportBranchName(b.config.BranchNameTemplate, backportPr.GetBase().GetRef(), backportPr.GetHead().GetRef())
+	if !ok {
+		return nil
+	}
+	githubactions.Infof("Pull request #%d backports #%d, recording its conflict resolutions", backportPr.GetNumber(), srcPrNumber)
+
+	srcPr, err := b.github.GetPR(ctx, srcPrNumber)
+	if err != nil {
+		return err
+	}
+	mk, commitSHAs, err := b.sourceCommits(ctx, srcPr)
+	if err != nil {
+		return err
+	}
+	if mk != github.Squash && len(commitSHAs) != 0 && b.config.MergeCommitHandling == MergeCommitHandlingSkip {
+		mergeCommitSHAs, err := b.git.FindCommitRange(ctx, "--merges", fmt.Sprintf("%s^..%s", commitSHAs[0], commitSHAs[len(commitSHAs)-1]))
+		if err != nil {
+			return err
+		}
+		commitSHAs = slices.DeleteFunc(commitSHAs, func(s string) bool { return slices.Contains(mergeCommitSHAs, s) })
+	}
+
+	// The backport branch was created from the target branch, so its base precedes all the commits of the backport PR.
+	head := backportPr.GetHead().GetSHA()
+	if err := b.git.Fetch(ctx, head, backportPr.GetCommits()); err != nil {
+		return err
+	}
+	prevHead, err := b.git.Head(ctx)
+	if err != nil {
+		return err
+	}
+	if err := b.git.Detach(ctx, fmt.Sprintf("%s~%d", head, backportPr.GetCommits())); err != nil {
+		return err
+	}
+	defer func() {
+		if err := b.git.SwitchBack(ctx, prevHead); err != nil {
+			githubactions.Warningf("Failed to switch back to %s after recording resolutions: %v", prevHead, err)
+		}
+	}()
+
+	recorded, err := b.git.RecordResolutions(ctx, head, commitSHAs...)
+	if err != nil {
+		return err
+	}
+	if recorded == 0 {
+		return nil
+	}
+	if b.plan != nil {
+		githubactions.Infof("[dry run] Would push %d recorded resolution(s) to %s", recorded, b.config.RerereRef)
+		return nil
+	}
+	return b.git.SaveRerere(ctx, b.config.RerereRef)
+}
@@ -0,0 +1,78 @@
+package backport
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// reviewersOf returns the users and teams to request a review from on the given backport PR of the source PR.
+//
+// These are the Reviewers of the given inputs, followed by the users involved in the source PR as selected by
+// ReviewFrom. Duplicates, bots and the author of the backport PR, who cannot review it, are left out. Failing to
+// look up the approvers of the source PR is not fatal, it's logged, and they are left out as well.
+func (b *backPorter) reviewersOf(ctx context.Context, cfg *Input, sourcePr, backportPr *v75github.PullRequest) []string {
+	reviewers := slices.Clone(cfg.Reviewers)
+	for _, from := range cfg.ReviewFrom {
+		switch from {
+		case ReviewFromAuthor:
+			reviewers = append(reviewers, loginOf(sourcePr.GetUser()))
+		case ReviewFromMerger:
+			reviewers = append(reviewers, loginOf(sourcePr.GetMergedBy()))
+		case ReviewFromApprovers:
+			approvers, err := b.github.GetApprovers(ctx, sourcePr)
+			if err != nil {
+				githubactions.Warningf("Failed to retrieve the approvers of PR #%d: %v", sourcePr.GetNumber(), err)
+			}
+			reviewers = append(reviewers, approvers...)
+		}
+	}
+
+	return uniqueLogins(reviewers, backportPr.GetUser().GetLogin())
+}
+
+// assigneesOf returns the users to assign to the backport PRs of the given source PR.
+//
+// These are the author of the source PR if AssignAuthor is set, followed by its assignees if CopyAssignees is set.
+// Bots are left out, as they cannot be assigned.
+func assigneesOf(cfg *Input, sourcePr *v75github.PullRequest) []string {
+	var assignees []string
+	if cfg.AssignAuthor {
+		assignees = append(assignees, loginOf(sourcePr.GetUser()))
+	}
+	if cfg.CopyAssignees {
+		for _, assignee := range sourcePr.Assignees {
+			assignees = append(assignees, loginOf(assignee))
+		}
+	}
+	return uniqueLogins(assignees, "")
+}
+
+// loginOf returns the login of the given user, or an empty string for bots such as dependabot[bot].
+//
+// GitHub rejects requesting a review from or assigning a bot, along with all the other users of the same request.
+func loginOf(user *v75github.User) string {
+	if user.GetType() == "Bot" {
+		return ""
+	}
+	return user.GetLogin()
+}
+
+// uniqueLogins returns the given logins without empty and duplicate ones and without the excluded login.
+//
+// Logins are compared case-insensitively, as GitHub does. The first occurrence of each login is kept.
+func uniqueLogins(logins []string, exclude string) []string {
+	var unique []string
+	for _, login := range logins {
+		if login == "" || strings.EqualFold(login, exclude) || slices.ContainsFunc(unique, func(l string) bool {
+			return strings.EqualFold(l, login)
+		}) {
+			continue
+		}
+		unique = append(unique, login)
+	}
+	return unique
+}
@@ -0,0 +1,136 @@
+package backport
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/yhabteab/backbot/git"
+)
+
+// legacyPlaceholders maps the "${...}" placeholders that predate the template engine to the equivalent actions,
+// so that they keep working in templates.
+var legacyPlaceholders = strings.NewReplacer(
+	"${target_branch}", "{{ .TargetBranch }}",
+	"${target_version}", "{{ .TargetVersion }}",
+	"${original_pr_number}", "{{ .SourcePR.Number }}",
+	"${original_pr_title}", "{{ .SourcePR.Title }}",
+	"${original_pr_description}", "{{ .SourcePR.Body }}",
+)
+
+// templateFuncs are the helper functions available in templates in addition to the builtin ones of text/template.
+//
+// The argument being operated on comes last, so that the functions can be used in pipelines, e.g.
+// `{{ .SourcePR.Labels | join ", " }}` or `{{ .SourcePR.Title | truncate 50 }}`.
+var templateFuncs = template.FuncMap{
+	"truncate": func(n int, s string) string {
+		if runes := []rune(s); len(runes) > n {
+			return string(runes[:max(n, 0)])
+		}
+		return s
+	},
+	"join":  func(sep string, elems []string) string { return strings.Join(elems, sep) },
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"short": shortSHA,
+}
+
+// commentKind classifies the comments backbot posts, so that comment templates can tell them apart.
+type commentKind string
+
+const (
+	commentSuccess  = commentKind("success")  // Backport PRs were created.
+	commentConflict = commentKind("conflict") // A backport conflicts and was aborted or created as draft.
+	commentFailure  = commentKind("failure")  // The backport was refused or failed altogether.
+	commentInfo     = commentKind("info")     // There's nothing to backport, e.g. all changes are already backported.
+	commentUpdate   = commentKind("update")   // An existing backport PR was updated by a re-run.
+)
+
+// templatePR describes a pull request in templates.
+type templatePR struct {
+	Number         int
+	Title          string
+	Body           string
+	URL            string
+	Author         string
+	Labels         []string
+	Milestone      string
+	MergeCommitSHA string
+	MergedBy       string
+}
+
+// templateData is the data templates are rendered with, e.g. the Title, Description and CommentTemplate.
+//
+// The fields that don't apply to a template are left empty, e.g. the Kind and Message outside of comments, or
+// the TargetBranch in comments summarizing the backports to all target branches.
+type templateData struct {
+	SourcePR        templatePR
+	TargetBranch    string
+	TargetVersion   string // The last version number in the TargetBranch, e.g. "2.14" for "support/2.14".
+	BackportBranch  string
+	Commits         []string             // The commits cherry-picked onto the target branch.
+	Conflict        bool                 // Whether cherry-picking the commits conflicts.
+	ConflictedFiles []git.ConflictedFile // The files that conflict, if any.
+	PendingCommits  []string             // The commits not cleanly cherry-picked due to a conflict, if any.
+	Kind            commentKind          // The kind of the comment.
+	Message         string               // The message backbot comments by default.
+}
+
+// newTemplateData returns the data to render templates for the given source PR and backport result with.
+//
+// The result may be nil, e.g. for comments that don't refer to a single target branch.
+func newTemplateData(sourcePr *v75github.PullRequest, result *backportResult) *templateData {
+	data := &templateData{
+		SourcePR: templatePR{
+			Number:         sourcePr.GetNumber(),
+			Title:          sourcePr.GetTitle(),
+			Body:           sourcePr.GetBody(),
+			URL:            sourcePr.GetHTMLURL(),
+			Author:         sourcePr.GetUser().GetLogin(),
+			Milestone:      sourcePr.GetMilestone().GetTitle(),
+			MergeCommitSHA: sourcePr.GetMergeCommitSHA(),
+			MergedBy:       sourcePr.GetMergedBy().GetLogin(),
+		},
+	}
+	for _, label := range sourcePr.Labels {
+		data.SourcePR.Labels = append(data.SourcePR.Labels, label.GetName())
+	}
+	if result != nil {
+		data.TargetBranch = result.target
+		data.TargetVersion = branchVersion(result.target)
+		data.BackportBranch = result.branch
+		data.Commits = result.commitSHAs
+		data.Conflict = result.conflict
+		data.ConflictedFiles = result.conflictedFiles
+		data.PendingCommits = result.pending
+	}
+	return data
+}
+
+// parseTemplate parses the given template text, in which the legacy "${...}" placeholders are supported as well.
+//
+// Returns an error if the text is not a valid template.
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(legacyPlaceholders.Replace(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// renderTemplate parses the given template text and renders it with the given data.
+//
+// Returns the rendered text, or an error if the text is not a valid template or cannot be rendered.
+func renderTemplate(name, text string, data *templateData) (string, error) {
+	tmpl, err := parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,59 @@
+package backport
+
+import (
+	"testing"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/stretchr/testify/require"
+	"github.com/yhabteab/backbot/git"
+)
+
+func TestTemplate(t *testing.T) {
+	sourcePr := &v75github.PullRequest{
+		Number:         v75github.Ptr(1),
+		Title:          v75github.Ptr("Add feature"),
+		Body:           v75github.Ptr("Adds a feature."),
+		User:           &v75github.User{Login: v75github.Ptr("alice")},
+		MergedBy:       &v75github.User{Login: v75github.Ptr("bob")},
+		Milestone:      &v75github.Milestone{Title: v75github.Ptr("v2.15")},
+		MergeCommitSHA: v75github.Ptr("0123456789abcdef"),
+		Labels:         []*v75github.Label{{Name: v75github.Ptr("area/core")}, {Name: v75github.Ptr("Bug")}},
+	}
+	result := &backportResult{
+		target:          "support/1.0",
+		branch:          "backport-1-to-support/1.0",
+		commitSHAs:      []string{"0123456789abcdef", "fedcba9876543210"},
+		conflict:        true,
+		conflictedFiles: []git.ConflictedFile{{Path: "README.md", Type: git.ConflictContent, Hunks: 1}},
+		pending:         []string{"fedcba9876543210"},
+	}
+
+	t.Run("LegacyPlaceholders", func(t *testing.T) {
+		text, err := renderTemplate("test", "[Backport ${target_branch}] ${original_pr_title} (#${original_pr_number})\n${original_pr_description}\nv${target_version}", newTemplateData(sourcePr, result))
+		require.NoError(t, err)
+		require.Equal(t, "[Backport support/1.0] Add feature (#1)\nAdds a feature.\nv1.0", text)
+	})
+
+	t.Run("Data", func(t *testing.T) {
+		text, err := renderTemplate("test", "{{ .SourcePR.Author }} {{ .SourcePR.MergedBy }} {{ .SourcePR.Milestone }} "+
+			"{{ .SourcePR.MergeCommitSHA | short }} {{ .BackportBranch }} {{ len .Commits }} {{ .Conflict }} "+
+			"{{ range .ConflictedFiles }}{{ .Path }} ({{ .Type }}){{ end }} {{ .PendingCommits | join \",\" | short }}",
+			newTemplateData(sourcePr, result))
+		require.NoError(t, err)
+		require.Equal(t, "alice bob v2.15 0123456 backport-1-to-support/1.0 2 true README.md (content) fedcba9", text)
+	})
+
+	t.Run("Funcs", func(t *testing.T) {
+		text, err := renderTemplate("test", `{{ .SourcePR.Labels | join ", " | lower }} {{ .SourcePR.Title | truncate 3 | upper }}`, newTemplateData(sourcePr, nil))
+		require.NoError(t, err)
+		require.Equal(t, "area/core, bug ADD", text)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := parseTemplate("test", "{{ .SourcePR.Title ")
+		require.ErrorContains(t, err, "failed to parse test template")
+
+		_, err = renderTemplate("test", "{{ .Unknown }}", newTemplateData(sourcePr, nil))
+		require.ErrorContains(t, err, "failed to render test template")
+	})
+}
@@ -2,17 +2,113 @@ package backport
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/google/go-github/v75/github"
 )
 
-// makeBackportBranchName constructs the name for the backport branch.
+// quotedPlaceholderRegex matches the placeholders of a template quoted by [regexp.QuoteMeta], e.g. `\$\{target_branch\}`.
+var quotedPlaceholderRegex = regexp.MustCompile(`\\\$\\\{\w+\\\}`)
+
+// makeBackportBranchName constructs the name for the backport branch from the given template.
+//
+// Besides the placeholders supported by [replacePlaceholders], the template may contain the following ones:
+// - ${original_pr_title_slug}: replaced with the slugified title of the original pull request, see [slugify].
+// - ${short_merge_sha}: replaced with the abbreviated SHA of the merge commit of the original pull request.
+//
+// It returns the constructed branch name, which is not guaranteed to be valid, see [validateBranchName].
+func makeBackportBranchName(template string, targetBranch string, sourcePr *github.PullRequest) string {
+	name := strings.ReplaceAll(template, "${original_pr_title_slug}", slugify(sourcePr.GetTitle()))
+	name = strings.ReplaceAll(name, "${short_merge_sha}", shortSHA(sourcePr.GetMergeCommitSHA()))
+	return replacePlaceholders(name, targetBranch, sourcePr)
+}
+
+// matchBackportBranchName checks whether the given branch name was constructed from the given template for the
+// given target branch, see [makeBackportBranchName].
+//
+// Returns the number of the original pull request, or false if the branch name doesn't match the template.
+func matchBackportBranchName(template, targetBranch, name string) (int64, bool) {
+	pattern := regexp.QuoteMeta(template)
+	for placeholder, replacement := range map[string]string{
+		"${target_branch}":      regexp.QuoteMeta(targetBranch),
+		"${original_pr_number}": `(\d+)`,
+	} {
+		pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta(placeholder), replacement)
+	}
+	// Any other placeholder may have been replaced with anything.
+	pattern = quotedPlaceholderRegex.ReplaceAllLiteralString(pattern, `.*`)
+
+	match := regexp.MustCompile("^" + pattern + "$").FindStringSubmatch(name)
+	if match == nil {
+		return 0, false
+	}
+	number, err := strconv.ParseInt(match[1], 10, 64)
+	return number, err == nil
+}
+
+// validateBranchName checks that the given name is a legal git branch name, as described by git-check-ref-format.
+//
+// Returns an error describing the first violated rule, if any.
+func validateBranchName(name string) error {
+	switch {
+	case name == "" || name == "@":
+		return fmt.Errorf("branch name %q is not allowed", name)
+	case strings.HasPrefix(name, "-"):
+		return fmt.Errorf("branch name %q must not begin with '-'", name)
+	case strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.Contains(name, "//"):
+		return fmt.Errorf("branch name %q must not begin or end with '/' or contain '//'", name)
+	case strings.HasSuffix(name, "."):
+		return fmt.Errorf("branch name %q must not end with '.'", name)
+	case strings.Contains(name, ".."):
+		return fmt.Errorf("branch name %q must not contain '..'", name)
+	case strings.Contains(name, "@{"):
+		return fmt.Errorf("branch name %q must not contain '@{'", name)
+	case strings.ContainsFunc(name, func(r rune) bool { return r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) }):
+		return fmt.Errorf("branch name %q must not contain control characters, spaces or any of '~^:?*[\\'", name)
+	}
+	for _, component := range strings.Split(name, "/") {
+		if strings.HasPrefix(component, ".") || strings.HasSuffix(component, ".lock") {
+			return fmt.Errorf("branch name %q must not have components beginning with '.' or ending with '.lock'", name)
+		}
+	}
+	return nil
+}
+
+// slugify turns the given text into a lowercase slug of at most 50 characters suitable for branch names.
 //
-// The branch name is formatted as "backport-{prNumber}-to-{targetBranch}".
-// It returns the constructed branch name.
-func makeBackportBranchName(prNumber int64, targetBranch string) string {
-	return fmt.Sprintf("backport-%d-to-%s", prNumber, targetBranch)
+// Any run of characters other than ASCII letters and digits is replaced with a single hyphen, e.g.
+// "Fix crash in Foo::bar()" becomes "fix-crash-in-foo-bar".
+func slugify(text string) string {
+	var sb strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(text) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && sb.Len() != 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+
+	const maxLen = 50
+	slug := sb.String()
+	if len(slug) > maxLen {
+		slug = strings.TrimRight(slug[:maxLen], "-")
+	}
+	return slug
+}
+
+// shortSHA returns the given commit SHA abbreviated to seven characters, like git does by default.
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
 }
 
 // replacePlaceholders replaces placeholders in the input string with actual values.
@@ -23,6 +119,8 @@ func makeBackportBranchName(prNumber int64, targetBranch string) string {
 // - ${original_pr_title}: replaced with the original pull request title.
 // - ${original_pr_description}: replaced with the original pull request description.
 //
+// This is only used for branch names, see [makeBackportBranchName], as the pull request title and description
+// as well as comments are rendered as templates, see [renderTemplate].
 // It returns the string with placeholders expanded to their corresponding values.
 func replacePlaceholders(value string, target string, sourcePr *github.PullRequest) string {
 	value = strings.ReplaceAll(value, "${target_branch}", target)
@@ -34,17 +132,38 @@ func replacePlaceholders(value string, target string, sourcePr *github.PullReque
 
 // makeNewPullRequest returns a fully initialized [github.NewPullRequest] object for creating a backport PR.
 //
-// The title and body are constructed based on the configuration and source PR details.
-// It returns the constructed [github.NewPullRequest] object.
-func (b *backPorter) makeNewPullRequest(sourcePr *github.PullRequest, target, backport string, draft bool) *github.NewPullRequest {
+// The title and body are rendered from the templates of the given inputs, see [renderTemplate]. Conflicts
+// encountered while cherry-picking the commits of the given result are noted at the end of the body for reviewers.
+// It returns the constructed [github.NewPullRequest] object, or an error if a template cannot be rendered.
+func makeNewPullRequest(cfg *Input, sourcePr *github.PullRequest, result *backportResult, backport string, draft bool) (*github.NewPullRequest, error) {
+	data := newTemplateData(sourcePr, result)
+	title, err := renderTemplate("pr_title", cfg.Title, data)
+	if err != nil {
+		return nil, err
+	}
+	body, err := renderTemplate("pr_description", cfg.Description, data)
+	if err != nil {
+		return nil, err
+	}
+
 	return &github.NewPullRequest{
-		Title:               github.Ptr(replacePlaceholders(b.config.Title, target, sourcePr)),
+		Title:               github.Ptr(strings.TrimSpace(title)),
 		Head:                github.Ptr(backport),
-		Base:                github.Ptr(target),
-		Body:                github.Ptr(replacePlaceholders(b.config.Description, target, sourcePr)),
+		Base:                github.Ptr(result.target),
+		Body:                github.Ptr(body + describeConflicts(result)),
 		MaintainerCanModify: github.Ptr(true),
 		Draft:               github.Ptr(draft),
+	}, nil
+}
+
+// seeLogs returns a sentence pointing to the logs of the given workflow run for details.
+//
+// If the run URL is empty, i.e. backbot isn't running in a workflow, the sentence refers to the logs in general.
+func seeLogs(runURL string) string {
+	if runURL == "" {
+		return "See the GitHub Actions logs for details."
 	}
+	return fmt.Sprintf("See the [workflow run](%s) for details.", runURL)
 }
 
 // listManualSteps generates a list of manual steps to resolve conflicts during backporting.
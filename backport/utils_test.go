@@ -0,0 +1,54 @@
+package backport
+
+import (
+	"testing"
+
+	v75github "github.com/google/go-github/v75/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackportBranchName(t *testing.T) {
+	pr := &v75github.PullRequest{
+		Number:         v75github.Ptr(42),
+		Title:          v75github.Ptr("Fix crash in Foo::bar() when the input is empty, which happens quite often"),
+		MergeCommitSHA: v75github.Ptr("0123456789abcdef0123456789abcdef01234567"),
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		const template = "backport-${original_pr_number}-to-${target_branch}"
+		name := makeBackportBranchName(template, "support/1.0", pr)
+		require.Equal(t, "backport-42-to-support/1.0", name)
+
+		number, ok := matchBackportBranchName(template, "support/1.0", name)
+		require.True(t, ok)
+		require.Equal(t, int64(42), number)
+		_, ok = matchBackportBranchName(template, "support/2.0", name)
+		require.False(t, ok)
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		const template = "backport/${target_branch}/${original_pr_number}-${original_pr_title_slug}-${short_merge_sha}"
+		name := makeBackportBranchName(template, "support/1.0", pr)
+		require.Equal(t, "backport/support/1.0/42-fix-crash-in-foo-bar-when-the-input-is-empty-which-0123456", name)
+		require.NoError(t, validateBranchName(name))
+
+		number, ok := matchBackportBranchName(template, "support/1.0", name)
+		require.True(t, ok)
+		require.Equal(t, int64(42), number)
+		_, ok = matchBackportBranchName(template, "support/1.0", "backport-42-to-support/1.0")
+		require.False(t, ok)
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		for _, name := range []string{"backport-1-to-support/1.0", "backport/support/1.0/1-fix", "feature@1"} {
+			require.NoError(t, validateBranchName(name), name)
+		}
+		for _, name := range []string{
+			"", "@", "-backport", "/backport", "backport/", "backport//1", "backport.", "backport..1", "backport@{1}",
+			"backport 1", "backport~1", "backport^1", "backport:1", "backport?", "backport*", "backport[1]", `backport\1`,
+			"backport\x7f", ".backport", "backport/.1", "backport.lock", "backport.lock/1",
+		} {
+			require.Error(t, validateBranchName(name), name)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package backport
+
+import (
+	"cmp"
+	"context"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sethvargo/go-githubactions"
+)
+
+// targetVersionRegex matches version numbers in target branch names, such as "2.14" in "support/2.14".
+var targetVersionRegex = regexp.MustCompile(`\d+(?:\.\d+)*`)
+
+// branchVersion returns the last version number in the given branch name, or an empty string if there's none.
+func branchVersion(branch string) string {
+	if versions := targetVersionRegex.FindAllString(branch, -1); len(versions) != 0 {
+		return versions[len(versions)-1]
+	}
+	return ""
+}
+
+// compareVersions compares the given dot-separated version numbers numerically, component by component.
+//
+// Missing components count as zero, so "2" and "2.0" are equal. Returns a negative number if a is lower
+// than b, a positive number if a is greater than b, and zero if they are equal.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range max(len(as), len(bs)) {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if c := cmp.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// supportedSince returns whether the given label requests backports to multiple supported branches, i.e. if it's
+// the AllSupportedLabel or matches the SinceLabelPattern.
+//
+// Returns the minimum version of the supported branches requested by the label, which is empty for all of them.
+func (in *Input) supportedSince(label string) (string, bool) {
+	if in.AllSupportedLabel != "" && label == in.AllSupportedLabel {
+		return "", true
+	}
+	if in.sinceLabelRegex != nil {
+		if matches := in.sinceLabelRegex.FindStringSubmatch(label); len(matches) > 1 {
+			return matches[1], true
+		}
+	}
+	return "", false
+}
+
+// supportedBranches returns the branches in the remote matching the SupportedBranchesPattern whose version is
+// greater than or equal to the given one, sorted by their version in ascending order.
+//
+// An empty version selects all supported branches. The branches of the remote are listed only once per run, and
+// the ones without a version are left out. Returns an error if the branches of the remote cannot be listed.
+func (b *backPorter) supportedBranches(ctx context.Context, since string) ([]string, error) {
+	if b.remoteBranches == nil {
+		branches, err := b.git.RemoteBranches(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b.remoteBranches = branches
+	}
+
+	versions := make(map[string]string)
+	var supported []string
+	for _, branch := range b.remoteBranches {
+		matches := b.config.supportedBranchesRegex.FindStringSubmatch(branch)
+		if len(matches) == 0 {
+			continue
+		}
+		version := branchVersion(branch)
+		if len(matches) > 1 {
+			version = matches[1]
+		}
+		if version == "" {
+			githubactions.Warningf("Supported branch '%s' has no version, skipping it", branch)
+			continue
+		}
+		if since == "" || compareVersions(version, since) >= 0 {
+			versions[branch] = version
+			supported = append(supported, branch)
+		}
+	}
+	slices.SortFunc(supported, func(a, b string) int {
+		return cmp.Or(compareVersions(versions[a], versions[b]), strings.Compare(a, b))
+	})
+	return supported, nil
+}
@@ -0,0 +1,40 @@
+package backport
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersions(t *testing.T) {
+	t.Run("Compare", func(t *testing.T) {
+		require.Zero(t, compareVersions("2.14", "2.14"))
+		require.Zero(t, compareVersions("2", "2.0"))
+		require.Negative(t, compareVersions("2.9", "2.10"))
+		require.Negative(t, compareVersions("1.10", "2.0"))
+		require.Positive(t, compareVersions("2.14.1", "2.14"))
+	})
+
+	t.Run("BranchVersion", func(t *testing.T) {
+		require.Equal(t, "2.14", branchVersion("support/2.14"))
+		require.Equal(t, "2.14", branchVersion("release-v1/2.14"))
+		require.Empty(t, branchVersion("main"))
+	})
+
+	t.Run("SupportedSince", func(t *testing.T) {
+		in := &Input{
+			AllSupportedLabel: "backport-to-all-supported",
+			sinceLabelRegex:   regexp.MustCompile(`^backport-since-(\d+(?:\.\d+)*)$`),
+		}
+
+		since, ok := in.supportedSince("backport-to-all-supported")
+		require.True(t, ok)
+		require.Empty(t, since)
+		since, ok = in.supportedSince("backport-since-2.13")
+		require.True(t, ok)
+		require.Equal(t, "2.13", since)
+		_, ok = in.supportedSince("backport-to-support/2.13")
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sethvargo/go-githubactions"
+	"github.com/yhabteab/backbot/backport"
+	"github.com/yhabteab/backbot/git"
+)
+
+// stringsFlag is a [flag.Value] that can be specified multiple times and also accepts comma-separated values.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string { return strings.Join(*s, ",") }
+
+func (s *stringsFlag) Set(value string) error {
+	for v := range strings.SplitSeq(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*s = append(*s, v)
+		}
+	}
+	return nil
+}
+
+// runBackportCmd runs the `backbot backport` subcommand with the given command line arguments.
+//
+// It builds the GitHub context from the flags instead of the GitHub Actions environment and runs the
+// same backport pipeline as the action in a local checkout of the repository. All the action inputs
+// can still be provided via their INPUT_* environment variables, e.g. INPUT_CONFLICT_HANDLING=draft.
+// The URLs of the GitHub instance default to the GITHUB_API_URL and GITHUB_SERVER_URL environment
+// variables like in a workflow, and to github.com if they aren't set either.
+func runBackportCmd(args []string) {
+	fs := flag.NewFlagSet("backport", flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: backbot backport --repo owner/name --pr 123 --to support/2.15 [options]\n\n")
+		fs.PrintDefaults()
+	}
+
+	var branches stringsFlag
+	repo := fs.String("repo", "", "The `owner/name` of the GitHub repository (required)")
+	prNumber := fs.Int64("pr", 0, "The `number` of the merged pull request to backport (required)")
+	workspace := fs.String("workspace", ".", "The `path` of the local checkout of the repository")
+	dryRun := fs.Bool("dry-run", false, "Only print the plan without pushing, creating PRs or commenting")
+	apiURL := fs.String("api-url", getenvOr("GITHUB_API_URL", "https://api.github.com"), "The `url` of the GitHub REST API")
+	serverURL := fs.String("server-url", getenvOr("GITHUB_SERVER_URL", "https://github.com"), "The `url` of the GitHub server")
+	fs.Var(&branches, "to", "The target `branch` to backport to, can be repeated or comma-separated (required)")
+	_ = fs.Parse(args) // ExitOnError handles all errors
+
+	owner, name, ok := strings.Cut(*repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		fatalUsage(fs, "--repo must be of the form owner/name, got: %q", *repo)
+	}
+	if *prNumber <= 0 {
+		fatalUsage(fs, "--pr must be a positive pull request number")
+	}
+	if len(branches) == 0 {
+		fatalUsage(fs, "at least one target branch must be given via --to")
+	}
+
+	dir, err := filepath.Abs(*workspace)
+	if err != nil {
+		githubactions.Fatalf("Failed to resolve workspace %s: %v", *workspace, err)
+	}
+
+	if os.Getenv("INPUT_GITHUB_TOKEN") == "" && os.Getenv("INPUT_APP_ID") == "" {
+		token, err := lookupToken()
+		if err != nil {
+			githubactions.Fatalf("Failed to find a GitHub token: %v", err)
+		}
+		_ = os.Setenv("INPUT_GITHUB_TOKEN", token)
+	}
+
+	cfg, err := backport.LoadInputsFromEnv()
+	if err != nil {
+		githubactions.Fatalf("Failed to load inputs from environment: %v", err)
+	}
+	cfg.DryRun = cfg.DryRun || *dryRun
+
+	ghCtx := &githubactions.GitHubContext{
+		Repository:      *repo,
+		RepositoryOwner: owner,
+		Workspace:       dir,
+		APIURL:          *apiURL,
+		ServerURL:       *serverURL,
+	}
+	// The git configuration of the developer must not be changed, so the committer is set for this run only.
+	if err := git.Configure(ghCtx, cfg.Committer, cfg.Email, false); err != nil {
+		githubactions.Fatalf("Failed to configure git: %v", err)
+	}
+	backport.RunRequest(context.Background(), cfg, ghCtx, &backport.Request{PrNumber: *prNumber, Branches: branches})
+}
+
+// lookupToken finds a GitHub token to use for the CLI mode.
+//
+// It prefers the GITHUB_TOKEN and GH_TOKEN environment variables and falls back to the token
+// stored by the GitHub CLI (`gh auth token`). Returns the token or an error if none is found.
+func lookupToken() (string, error) {
+	for _, env := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := os.Getenv(env); token != "" {
+			return token, nil
+		}
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("gh", "auth", "token")
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", errors.New("neither GITHUB_TOKEN nor GH_TOKEN is set and the gh CLI is not installed")
+		}
+		return "", fmt.Errorf("gh auth token: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// getenvOr returns the value of the given environment variable, or the given fallback if it's unset or empty.
+func getenvOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// fatalUsage prints the given error message followed by the usage of the flag set and exits.
+func fatalUsage(fs *flag.FlagSet, format string, args ...any) {
+	_, _ = fmt.Fprintf(fs.Output(), "Error: "+format+"\n\n", args...)
+	fs.Usage()
+	os.Exit(2)
+}
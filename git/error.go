@@ -3,6 +3,7 @@ package git
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // ErrGitOp represents an error that occurred during a git operation.
@@ -30,3 +31,44 @@ func IsConflictErr(err error) bool {
 	var gitErr *ErrGitOp
 	return errors.As(err, &gitErr) && gitErr.Status == 1
 }
+
+// ErrConflict represents a conflict that occurred while cherry-picking a commit.
+type ErrConflict struct {
+	Commit string           // The commit that conflicts, if known.
+	Files  []ConflictedFile // The files that conflict.
+	Err    error            // The underlying error of the git operation.
+}
+
+// Error returns a formatted error message for the ErrConflict, including the conflicting files.
+func (e *ErrConflict) Error() string {
+	var files []string
+	for _, file := range e.Files {
+		files = append(files, fmt.Sprintf("%s (%s)", file.Path, file.Type))
+	}
+	commit := e.Commit
+	if commit == "" {
+		commit = "unknown"
+	}
+	return fmt.Sprintf("commit %s conflicts in %s: %s", commit, strings.Join(files, ", "), e.Err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (e *ErrConflict) Unwrap() error { return e.Err }
+
+// ConflictedFiles returns the conflicting files of the given error, if it's caused by an [ErrConflict].
+func ConflictedFiles(err error) []ConflictedFile {
+	var conflictErr *ErrConflict
+	if errors.As(err, &conflictErr) {
+		return conflictErr.Files
+	}
+	return nil
+}
+
+// ConflictingCommit returns the conflicting commit of the given error, if it's caused by an [ErrConflict].
+func ConflictingCommit(err error) string {
+	var conflictErr *ErrConflict
+	if errors.As(err, &conflictErr) {
+		return conflictErr.Commit
+	}
+	return ""
+}
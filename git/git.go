@@ -2,10 +2,15 @@ package git
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,15 +20,47 @@ import (
 // Git holds configuration for git operations.
 type Git struct {
 	githubCtx *githubactions.GitHubContext
+
+	// authToken returns the token to authenticate against the GitHub server with, if set.
+	authToken func(context.Context) (string, error)
+
+	env    []string // Additional environment variables of the git commands, e.g. GIT_INDEX_FILE.
+	rerere bool     // Whether recorded resolutions are reused, see [Git.EnableRerere].
 }
 
 // NewGit creates a new Git instance with the provided committer name and email.
 func NewGit(ghCtx *githubactions.GitHubContext) *Git { return &Git{githubCtx: ghCtx} }
 
+// WithAuthToken returns a copy of the Git instance whose commands authenticate against the GitHub server with
+// the token returned by the given function, instead of the credentials persisted by actions/checkout.
+//
+// The function is called for every command, so that it can refresh short-lived tokens as needed.
+func (g *Git) WithAuthToken(token func(context.Context) (string, error)) *Git {
+	g2 := *g
+	g2.authToken = token
+	return &g2
+}
+
 // Configure sets up git with the specified committer name and email, and marks the workspace as a safe directory.
-func Configure(ghCtx *githubactions.GitHubContext, committer, email string) error {
+//
+// If global is false, e.g. in the checkout of a developer, neither the global nor the repository configuration is
+// changed. The committer is then only set for the git commands of this process via the GIT_COMMITTER_NAME and
+// GIT_COMMITTER_EMAIL environment variables, and the workspace is left as is, as it's owned by the developer anyway.
+func Configure(ghCtx *githubactions.GitHubContext, committer, email string, global bool) error {
 	githubactions.Group("Configuring git")
 	defer githubactions.EndGroup()
+	if !global {
+		githubactions.Infof("Configuring git committer name and email for this run only")
+		if err := os.Setenv("GIT_COMMITTER_NAME", committer); err != nil {
+			return fmt.Errorf("failed to set git committer name: %w", err)
+		}
+		if err := os.Setenv("GIT_COMMITTER_EMAIL", email); err != nil {
+			return fmt.Errorf("failed to set git committer email: %w", err)
+		}
+		githubactions.Infof("Configured git with committer '%s' and email '%s'", committer, email)
+		return nil
+	}
+
 	githubactions.Infof("Marking GitHub workspace '%s' as a safe directory", ghCtx.Workspace)
 	g := NewGit(ghCtx)
 	// Mark the current workspace as a safe directory to avoid some weird git errors [^1].
@@ -52,9 +89,21 @@ func Configure(ghCtx *githubactions.GitHubContext, committer, email string) erro
 //
 // The depth is increased by one to ensure that we have enough commit history for operations like
 // finding commit ranges or cherry-picking, which may require knowledge of parent commits.
+//
+// The depth is only applied to shallow repositories, such as the ones created by actions/checkout.
+// Complete repositories, e.g. a developer's local clone, are never turned into shallow ones.
 func (g *Git) Fetch(ctx context.Context, ref string, depth int) error {
 	githubactions.Group(fmt.Sprintf("Fetching %s", ref))
 	defer githubactions.EndGroup()
+
+	shallow, err := g.isShallow(ctx)
+	if err != nil {
+		return err
+	}
+	if !shallow {
+		githubactions.Infof("Fetching from remote origin, ref %s", ref)
+		return g.runCmd(ctx, "fetch", "origin", ref)
+	}
 	githubactions.Infof("Fetching from remote origin, ref %s, depth %d", ref, depth)
 	return g.runCmd(ctx, "fetch", "--depth", fmt.Sprint(depth+1), "origin", ref)
 }
@@ -67,14 +116,79 @@ func (g *Git) Push(ctx context.Context, ref string) error {
 	return g.runCmd(ctx, "push", "--set-upstream", "origin", ref)
 }
 
-// Checkout checks out the specified branch.
-func (g *Git) Checkout(ctx context.Context, ref, startPoint string) error {
+// ForcePush is like [Git.Push], but overwrites the branch in the remote, as long as it still points to
+// the commit last fetched into its remote-tracking branch.
+func (g *Git) ForcePush(ctx context.Context, ref string) error {
+	githubactions.Group(fmt.Sprintf("Force-pushing %s", ref))
+	defer githubactions.EndGroup()
+	githubactions.Infof("Force-pushing branch %s to remote origin", ref)
+	return g.runCmd(ctx, "push", "--force-with-lease", "--set-upstream", "origin", ref)
+}
+
+// RemoteBranchExists checks whether the given branch exists in the remote origin.
+//
+// Returns an error if the remote cannot be queried.
+func (g *Git) RemoteBranchExists(ctx context.Context, branch string) (bool, error) {
+	return g.remoteRefExists(ctx, "refs/heads/"+branch)
+}
+
+// RemoteBranches lists the names of all branches in the remote origin, without fetching any of them.
+//
+// Returns an error if the remote cannot be queried.
+func (g *Git) RemoteBranches(ctx context.Context) ([]string, error) {
+	output, err := g.output(ctx, "ls-remote", "--heads", "origin")
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []string
+	for line := range strings.Lines(output) {
+		if _, ref, ok := strings.Cut(strings.TrimSpace(line), "\t"); ok {
+			branches = append(branches, strings.TrimPrefix(ref, "refs/heads/"))
+		}
+	}
+	return branches, nil
+}
+
+// Checkout creates the specified branch from the start point and checks it out.
+//
+// If the branch already exists locally, it's reset to the start point if force is true, and an error is returned
+// otherwise, so that local work on the branch, e.g. in a developer's checkout, isn't discarded by accident.
+func (g *Git) Checkout(ctx context.Context, ref, startPoint string, force bool) error {
 	githubactions.Group(fmt.Sprintf("Checking out %s", ref))
 	defer githubactions.EndGroup()
 	githubactions.Infof("Checking out branch %s from %s", ref, startPoint)
+	if force {
+		return g.runCmd(ctx, "switch", "--force-create", ref, startPoint)
+	}
 	return g.runCmd(ctx, "switch", "--create", ref, startPoint)
 }
 
+// Detach checks out the given start point without creating a branch for it, i.e. with a detached HEAD.
+func (g *Git) Detach(ctx context.Context, startPoint string) error {
+	githubactions.Infof("Checking out %s with a detached HEAD", startPoint)
+	return g.runCmd(ctx, "switch", "--detach", startPoint)
+}
+
+// Head returns the name of the branch checked out in the workspace, or the SHA of the commit if HEAD is detached.
+//
+// The result can be checked out again with [Git.SwitchBack].
+func (g *Git) Head(ctx context.Context) (string, error) {
+	if branch, err := g.output(ctx, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
+		return strings.TrimSpace(branch), nil
+	}
+	sha, err := g.output(ctx, "rev-parse", "--verify", "HEAD")
+	return strings.TrimSpace(sha), err
+}
+
+// SwitchBack checks out the given branch or commit as returned by [Git.Head].
+//
+// Fails rather than discarding local changes that would be overwritten by the checkout.
+func (g *Git) SwitchBack(ctx context.Context, head string) error {
+	githubactions.Infof("Switching back to %s", head)
+	return g.runCmd(ctx, "checkout", "--quiet", head)
+}
+
 // CherryPick applies the commit with the given hash to the current branch.
 //
 // This method cherry-picks each commit in the provided order, even if some of them are empty.
@@ -83,12 +197,29 @@ func (g *Git) Checkout(ctx context.Context, ref, startPoint string) error {
 //
 // If a conflict occurs during cherry-picking, it will attempt to abort the operation before returning an error.
 func (g *Git) CherryPick(ctx context.Context, commitOnConflict bool, commits ...string) error {
+	return g.cherryPick(ctx, commitOnConflict, nil, commits...)
+}
+
+// CherryPickWithOption is like [Git.CherryPick], but passes the given option to the merge strategy,
+// e.g. "theirs" or "diff-algorithm=histogram", and never commits on conflict.
+func (g *Git) CherryPickWithOption(ctx context.Context, option string, commits ...string) error {
+	return g.cherryPick(ctx, false, []string{"--strategy-option=" + option}, commits...)
+}
+
+// cherryPick cherry-picks the given commits with the given additional arguments, see [Git.CherryPick].
+func (g *Git) cherryPick(ctx context.Context, commitOnConflict bool, args []string, commits ...string) error {
 	githubactions.Group(fmt.Sprintf("CherryPicking %d commits", len(commits)))
 	defer githubactions.EndGroup()
-	cmd := append([]string{"cherry-pick", "--empty=drop", "--allow-empty", "-x"}, commits...)
+	cmd := append([]string{"cherry-pick", "--empty=drop", "--allow-empty", "-x"}, args...)
+	cmd = append(cmd, commits...)
 	githubactions.Infof("Cherry-picking commits using git %v", cmd)
 
-	if err := g.runCmd(ctx, cmd...); err != nil {
+	if err := g.runCherryPick(ctx, len(commits), cmd...); err != nil {
+		if IsConflictErr(err) {
+			// Collect the conflicting files before aborting, as they are gone afterward.
+			err = g.conflictErr(ctx, err)
+		}
+
 		// Attempt to always abort cherry-pick on error, otherwise this will lead to some weird permission
 		// error when trying to commit and push the current state with the cherry-pick in progress. Instead,
 		// just create an empty draft commit without any changes that the user can resolve manually.
@@ -108,6 +239,166 @@ func (g *Git) CherryPick(ctx context.Context, commitOnConflict bool, commits ...
 	return nil
 }
 
+// runCherryPick runs the given cherry-pick command of the given number of commits.
+//
+// If rerere is enabled and the cherry-pick stops at a commit whose conflicts have all been resolved using
+// recorded resolutions, the cherry-pick is continued, at most once for each of the commits.
+func (g *Git) runCherryPick(ctx context.Context, commits int, args ...string) error {
+	err := g.runCmd(ctx, args...)
+	for range commits {
+		if !g.rerere || err == nil || !IsConflictErr(err) {
+			break
+		}
+		if files, filesErr := g.conflictedFiles(ctx); filesErr != nil || len(files) != 0 {
+			break
+		}
+		githubactions.Infof("All conflicts were resolved using recorded resolutions, continuing cherry-pick")
+		err = g.runCmd(ctx, "-c", "core.editor=true", "cherry-pick", "--continue")
+	}
+	return err
+}
+
+// ConflictType describes the kind of conflict of a file.
+type ConflictType string
+
+const (
+	ConflictContent      = ConflictType("content")       // Both sides modified the same lines of the file.
+	ConflictAddAdd       = ConflictType("add/add")       // Both sides added the file with different contents.
+	ConflictModifyDelete = ConflictType("modify/delete") // One side modified the file, the other one deleted it.
+	ConflictRename       = ConflictType("rename")        // At least one side renamed the file, e.g. rename/delete.
+)
+
+// ConflictedFile describes a file that conflicts when cherry-picking a commit.
+type ConflictedFile struct {
+	Path  string       // The path of the file relative to the repository root.
+	Type  ConflictType // The kind of conflict.
+	Hunks int          // The number of conflict marker hunks in the file, zero for e.g. deleted or binary files.
+}
+
+// CherryPickWithMarkers cherry-picks the given commit like [Git.CherryPick], but if it conflicts, the conflicted
+// working tree including the conflict markers is committed instead of an empty draft commit.
+//
+// Returns the conflicted files, if any, or an error if the operation fails.
+func (g *Git) CherryPickWithMarkers(ctx context.Context, commit string) ([]ConflictedFile, error) {
+	githubactions.Group(fmt.Sprintf("CherryPicking %s", commit))
+	defer githubactions.EndGroup()
+	githubactions.Infof("Cherry-picking commit %s, keeping conflict markers", commit)
+
+	err := g.runCherryPick(ctx, 1, "cherry-pick", "--empty=drop", "--allow-empty", "-x", commit)
+	if err == nil {
+		return nil, nil
+	}
+
+	var files []ConflictedFile
+	if IsConflictErr(err) {
+		files, err = g.conflictedFiles(ctx)
+	}
+	if err != nil || len(files) == 0 {
+		if abortErr := g.runCmd(ctx, "cherry-pick", "--abort"); abortErr != nil {
+			githubactions.Warningf("Failed to abort cherry-pick after error: %v", abortErr)
+		}
+		if err == nil {
+			err = fmt.Errorf("cherry-pick of commit %s failed without conflicting files", commit)
+		}
+		return nil, fmt.Errorf("failed to cherry-pick commit %s: %w", commit, err)
+	}
+
+	githubactions.Warningf("Conflict occurred while cherry-picking commit %s, committing %d file(s) with conflict markers", commit, len(files))
+	// Stage the files including their conflict markers and forget about the cherry-pick in progress, so that
+	// the result can be committed like any other change. Only the conflicting files are staged, as the other
+	// changes of the commit already are, and any untracked files in the workspace must not be committed.
+	add := []string{"add", "--all", "--"}
+	for _, file := range files {
+		add = append(add, file.Path)
+	}
+	if err := g.runCmd(ctx, add...); err != nil {
+		return nil, fmt.Errorf("failed to stage conflicting files: %w", err)
+	}
+	if err := g.runCmd(ctx, "cherry-pick", "--quit"); err != nil {
+		return nil, fmt.Errorf("failed to quit cherry-pick: %w", err)
+	}
+	// The commit must not carry the trailer of `git cherry-pick -x`, as it hasn't been applied completely,
+	// see [Git.FindApplied].
+	message := fmt.Sprintf("Backport commit with conflict markers, needs manual resolution\n\nConflicting commit: %s", commit)
+	if err := g.runCmd(ctx, "commit", "--no-verify", "--message", message); err != nil {
+		return nil, fmt.Errorf("failed to commit conflicting files: %w", err)
+	}
+	return files, nil
+}
+
+// conflictErr returns an [ErrConflict] wrapping the given error of a conflicting cherry-pick that's in progress.
+//
+// If the conflicting files cannot be determined, the given error is returned as is.
+func (g *Git) conflictErr(ctx context.Context, err error) error {
+	files, filesErr := g.conflictedFiles(ctx)
+	if filesErr != nil {
+		githubactions.Warningf("Failed to determine the conflicting files: %v", filesErr)
+		return err
+	}
+	if len(files) == 0 {
+		return err
+	}
+
+	// CHERRY_PICK_HEAD points to the commit that conflicts, even when cherry-picking multiple commits.
+	commit, revErr := g.output(ctx, "rev-parse", "--verify", "--quiet", "CHERRY_PICK_HEAD")
+	if revErr != nil {
+		githubactions.Warningf("Failed to determine the conflicting commit: %v", revErr)
+	}
+	return &ErrConflict{Commit: strings.TrimSpace(commit), Files: files, Err: err}
+}
+
+// conflictedFiles returns the files with unresolved conflicts in the workspace, sorted by their path.
+//
+// The type of each conflict is derived from the index stages present for the file, i.e. whether the file
+// exists in the common ancestor (stage 1), our (stage 2) and their (stage 3) version. The conflict hunks
+// are counted by the conflict markers starting each of them.
+func (g *Git) conflictedFiles(ctx context.Context) ([]ConflictedFile, error) {
+	output, err := g.output(ctx, "ls-files", "--unmerged", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	stages := make(map[string]string)
+	for entry := range strings.SplitSeq(output, "\x00") {
+		// Each entry has the form "<mode> <object> <stage>\t<path>".
+		info, path, ok := strings.Cut(entry, "\t")
+		if !ok {
+			continue
+		}
+		if _, ok := stages[path]; !ok {
+			paths = append(paths, path)
+		}
+		stages[path] += info[strings.LastIndexByte(info, ' ')+1:]
+	}
+
+	var files []ConflictedFile
+	for _, path := range paths {
+		file := ConflictedFile{Path: path}
+		switch stages[path] {
+		case "123":
+			file.Type = ConflictContent
+		case "23":
+			file.Type = ConflictAddAdd
+		case "12", "13":
+			file.Type = ConflictModifyDelete
+		default: // Added or deleted by one or both sides only, which git reports for conflicting renames.
+			file.Type = ConflictRename
+		}
+
+		// A file may not exist in the working tree, e.g. if it was deleted on one side.
+		if content, err := os.ReadFile(filepath.Join(g.githubCtx.Workspace, path)); err == nil {
+			for line := range strings.Lines(string(content)) {
+				if strings.HasPrefix(line, "<<<<<<< ") {
+					file.Hunks++
+				}
+			}
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
 // FindCommitRange finds the range of commits between the given base and head commit hashes.
 //
 // It returns a slice of commit hashes in chronological order (from oldest to newest).
@@ -116,24 +407,151 @@ func (g *Git) FindCommitRange(ctx context.Context, args ...string) ([]string, er
 	defer githubactions.EndGroup()
 	githubactions.Infof("Finding commit range for %s using git rev-list", args)
 
+	output, err := g.output(ctx, append([]string{"rev-list", "--reverse"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	commits := strings.Fields(output)
+	githubactions.Infof("Found commits in range %v: %v", args, commits)
+	return commits, nil
+}
+
+// cherryPickedRegex matches the trailer that `git cherry-pick -x` adds to the message of the picked commit.
+var cherryPickedRegex = regexp.MustCompile(`\(cherry picked from commit ([0-9a-f]{40})\)`)
+
+// FindApplied finds the given commits that have already been applied to the given target ref.
+//
+// A commit counts as applied if it's an ancestor of the target ref, if one of the most recent commits of the
+// target ref references it with the "(cherry picked from commit ...)" trailer added by `git cherry-pick -x`,
+// or if one of them has the same patch ID [^1], i.e. introduces the very same changes. Only the given number
+// of the most recent commits of the target ref are searched.
+//
+// Returns a map of the applied commits to the commits of the target ref they have been applied as.
+//
+// [^1]: https://git-scm.com/docs/git-patch-id
+func (g *Git) FindApplied(ctx context.Context, target string, depth int, commits ...string) (map[string]string, error) {
+	githubactions.Group(fmt.Sprintf("Finding commits already applied to %s", target))
+	defer githubactions.EndGroup()
+
+	applied := make(map[string]string)
+	for _, commit := range commits {
+		err := g.runCmd(ctx, "merge-base", "--is-ancestor", commit, target)
+		if err == nil {
+			githubactions.Infof("Commit %s is an ancestor of %s", commit, target)
+			applied[commit] = commit
+			continue
+		}
+		var gitErr *ErrGitOp
+		if !errors.As(err, &gitErr) || gitErr.Status != 1 { // The exit status if the commit is not an ancestor.
+			return nil, err
+		}
+	}
+
+	history := []string{"--max-count", fmt.Sprint(depth), "--no-merges", target}
+	messages, err := g.output(ctx, append([]string{"log", "--format=%H %B%x00"}, history...)...)
+	if err != nil {
+		return nil, err
+	}
+	for message := range strings.SplitSeq(messages, "\x00") {
+		sha, body, _ := strings.Cut(strings.TrimSpace(message), " ")
+		for _, match := range cherryPickedRegex.FindAllStringSubmatch(body, -1) {
+			if slices.Contains(commits, match[1]) && applied[match[1]] == "" {
+				githubactions.Infof("Commit %s has been cherry-picked to %s as %s", match[1], target, sha)
+				applied[match[1]] = sha
+			}
+		}
+	}
+
+	targetIDs, err := g.patchIDs(ctx, history...)
+	if err != nil {
+		return nil, err
+	}
+	commitIDs, err := g.patchIDs(ctx, append([]string{"--no-walk"}, commits...)...)
+	if err != nil {
+		return nil, err
+	}
+	for patchID, commit := range commitIDs {
+		if sha, ok := targetIDs[patchID]; ok && applied[commit] == "" {
+			githubactions.Infof("Commit %s has been applied to %s as %s with the same patch ID", commit, target, sha)
+			applied[commit] = sha
+		}
+	}
+	return applied, nil
+}
+
+// patchIDs computes the stable patch IDs of the commits listed by `git log` with the given arguments.
+//
+// Returns a map of the patch IDs to the commits. Commits without changes, such as merges, have no patch ID.
+func (g *Git) patchIDs(ctx context.Context, args ...string) (map[string]string, error) {
+	patches, err := g.output(ctx, append([]string{"log", "--patch", "--no-color", "--no-ext-diff"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	output, err := g.outputWithInput(ctx, strings.NewReader(patches), "patch-id", "--stable")
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string)
+	for line := range strings.Lines(output) {
+		if patchID, commit, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+			ids[patchID] = commit
+		}
+	}
+	return ids, nil
+}
+
+// remoteRefExists checks whether the given fully qualified ref exists in the remote origin.
+func (g *Git) remoteRefExists(ctx context.Context, ref string) (bool, error) {
+	if _, err := g.output(ctx, "ls-remote", "--exit-code", "origin", ref); err != nil {
+		var gitErr *ErrGitOp
+		if errors.As(err, &gitErr) && gitErr.Status == 2 { // The exit status if the ref doesn't exist.
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// isShallow checks whether the repository in the workspace is a shallow clone.
+func (g *Git) isShallow(ctx context.Context) (bool, error) {
+	output, err := g.output(ctx, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(output) == "true", nil
+}
+
+// output runs a git command with the specified arguments and returns its standard output.
+//
+// Unlike runCmd, the standard output is captured instead of being redirected to the GitHub Actions
+// logs, while the standard error is discarded.
+func (g *Git) output(ctx context.Context, args ...string) (string, error) {
+	return g.outputWithInput(ctx, nil, args...)
+}
+
+// outputWithInput is like output, but feeds the given input, if any, to the standard input of the command.
+func (g *Git) outputWithInput(ctx context.Context, input io.Reader, args ...string) (string, error) {
 	// Set a timeout to avoid hanging indefinitely
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	cmd := g.prepareCMD(ctx, append([]string{"rev-list", "--reverse"}, args...)...)
+	cmd := g.prepareCMD(ctx, args...)
+	if err := g.authenticate(ctx, cmd); err != nil {
+		return "", err
+	}
+	cmd.Stdin = input
 	cmd.Stderr = nil // We want to handle exit errors down below
 	cmd.Stdout = nil // We want to capture the output
 	output, err := cmd.Output()
 	if err != nil {
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) {
-			return nil, NewErrGitOp(strings.Join(cmd.Args, " "), err, exitErr.ExitCode())
+			return "", NewErrGitOp(strings.Join(cmd.Args, " "), err, exitErr.ExitCode())
 		}
-		return nil, fmt.Errorf("failed to run git rev-list: %w", err)
+		return "", fmt.Errorf("failed to run git %s: %w", args[0], err)
 	}
-	commits := strings.Fields(string(output))
-	githubactions.Infof("Found commits in range %v: %v", args, commits)
-	return commits, nil
+	return string(output), nil
 }
 
 // runCmd runs a git command with the specified arguments.
@@ -149,6 +567,9 @@ func (g *Git) runCmd(ctx context.Context, args ...string) error {
 	defer cancel()
 
 	cmd := g.prepareCMD(ctx, args...)
+	if err := g.authenticate(ctx, cmd); err != nil {
+		return err
+	}
 	if err := cmd.Run(); err != nil {
 		return NewErrGitOp(strings.Join(cmd.Args, " "), err, cmd.ProcessState.ExitCode())
 	}
@@ -170,7 +591,7 @@ func (g *Git) runCmd(ctx context.Context, args ...string) error {
 // and output redirection. It does not run the command; it only prepares it for execution.
 func (g *Git) prepareCMD(ctx context.Context, args ...string) *exec.Cmd {
 	cmd := exec.CommandContext(ctx, "git", args...)
-	cmd.Env = os.Environ()
+	cmd.Env = append(os.Environ(), g.env...)
 	// Redirect everything to the standard output where GitHub Actions can capture it
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stdout
@@ -178,3 +599,31 @@ func (g *Git) prepareCMD(ctx context.Context, args ...string) *exec.Cmd {
 	cmd.Dir = g.githubCtx.Workspace
 	return cmd
 }
+
+// authenticate makes the given command authenticate against the GitHub server with the configured token, if any.
+//
+// actions/checkout persists its credentials as an extra HTTP header for the GitHub server in the local git config [^1].
+// That header is reset and replaced by one with the configured token via the environment of the command, so that
+// the token is neither written to disk nor visible in the command line.
+//
+// [^1]: https://github.com/actions/checkout#usage
+func (g *Git) authenticate(ctx context.Context, cmd *exec.Cmd) error {
+	if g.authToken == nil {
+		return nil
+	}
+	token, err := g.authToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve git credentials: %w", err)
+	}
+
+	key := fmt.Sprintf("http.%s/.extraheader", strings.TrimSuffix(g.githubCtx.ServerURL, "/"))
+	credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	cmd.Env = append(cmd.Env,
+		"GIT_CONFIG_COUNT=2",
+		"GIT_CONFIG_KEY_0="+key,
+		"GIT_CONFIG_VALUE_0=", // An empty value resets the headers configured so far.
+		"GIT_CONFIG_KEY_1="+key,
+		"GIT_CONFIG_VALUE_1=AUTHORIZATION: basic "+credentials,
+	)
+	return nil
+}
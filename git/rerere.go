@@ -0,0 +1,233 @@
+package git
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sethvargo/go-githubactions"
+)
+
+// EnableRerere enables git's "reuse recorded resolution" feature in the workspace [^1].
+//
+// Once enabled, conflicts that git resolves using recorded resolutions are staged automatically, and
+// cherry-picks that stop only due to such conflicts are continued rather than reported as conflicts.
+//
+// [^1]: https://git-scm.com/book/en/v2/Git-Tools-Rerere
+func (g *Git) EnableRerere(ctx context.Context) error {
+	githubactions.Infof("Enabling git rerere to reuse recorded conflict resolutions")
+	if err := g.runCmd(ctx, "config", "rerere.enabled", "true"); err != nil {
+		return fmt.Errorf("failed to enable rerere: %w", err)
+	}
+	if err := g.runCmd(ctx, "config", "rerere.autoUpdate", "true"); err != nil {
+		return fmt.Errorf("failed to enable rerere auto update: %w", err)
+	}
+	g.rerere = true
+	return nil
+}
+
+// RestoreRerere restores the recorded resolutions from the given ref of the remote origin into the workspace.
+//
+// It's not an error if the ref doesn't exist yet, in which case nothing is restored.
+func (g *Git) RestoreRerere(ctx context.Context, ref string) error {
+	githubactions.Group(fmt.Sprintf("Restoring recorded resolutions from %s", ref))
+	defer githubactions.EndGroup()
+
+	exists, err := g.remoteRefExists(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		githubactions.Infof("No recorded resolutions found at %s", ref)
+		return nil
+	}
+	if err := g.runCmd(ctx, "fetch", "--no-tags", "origin", fmt.Sprintf("+%s:%[1]s", ref)); err != nil {
+		return err
+	}
+
+	rrCache, err := g.rrCache(ctx)
+	if err != nil {
+		return err
+	}
+	archive, err := g.output(ctx, "archive", "--format=tar", ref)
+	if err != nil {
+		return err
+	}
+
+	restored := 0
+	tr := tar.NewReader(strings.NewReader(archive))
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read recorded resolutions: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || !filepath.IsLocal(header.Name) {
+			continue
+		}
+
+		path := filepath.Join(rrCache, filepath.FromSlash(header.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("failed to read recorded resolution %s: %w", header.Name, err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return err
+		}
+		restored++
+	}
+	githubactions.Infof("Restored %d file(s) of recorded resolutions from %s", restored, ref)
+	return nil
+}
+
+// RecordResolutions records how the conflicts of cherry-picking the given commits onto the current branch
+// were resolved in the given resolution commit, e.g. the head of a merged backport branch.
+//
+// The commits are cherry-picked in turn, and the files of each conflicting one are taken from the resolution
+// commit, so that rerere records their resolutions. Requires rerere to be enabled, see [Git.EnableRerere].
+//
+// Returns the number of recorded resolutions, or an error if the operation fails.
+func (g *Git) RecordResolutions(ctx context.Context, resolution string, commits ...string) (int, error) {
+	githubactions.Group(fmt.Sprintf("Recording conflict resolutions of %s", resolution))
+	defer githubactions.EndGroup()
+
+	recorded := 0
+	for _, commit := range commits {
+		err := g.runCherryPick(ctx, 1, "cherry-pick", "--empty=drop", "--allow-empty", "-x", commit)
+		if err == nil {
+			continue
+		}
+
+		var files []ConflictedFile
+		if IsConflictErr(err) {
+			files, err = g.conflictedFiles(ctx)
+		}
+		if err == nil && len(files) == 0 {
+			err = fmt.Errorf("cherry-pick of commit %s failed without conflicting files", commit)
+		}
+		if err == nil {
+			for _, file := range files {
+				if _, catErr := g.output(ctx, "cat-file", "-e", resolution+":"+file.Path); catErr != nil {
+					err = g.runCmd(ctx, "rm", "--quiet", "--", file.Path) // The resolution deleted the file.
+				} else {
+					err = g.runCmd(ctx, "checkout", resolution, "--", file.Path)
+				}
+				if err != nil {
+					break
+				}
+				if file.Hunks != 0 { // Rerere only records resolutions of conflict hunks.
+					recorded++
+				}
+			}
+		}
+		if err == nil {
+			err = g.commitResolved(ctx, commit)
+		}
+		if err != nil {
+			if abortErr := g.runCmd(ctx, "cherry-pick", "--abort"); abortErr != nil {
+				githubactions.Warningf("Failed to abort cherry-pick after error: %v", abortErr)
+			}
+			return recorded, fmt.Errorf("failed to record resolutions for commit %s: %w", commit, err)
+		}
+	}
+	githubactions.Infof("Recorded %d conflict resolution(s)", recorded)
+	return recorded, nil
+}
+
+// commitResolved records the resolutions of the cherry-pick of the given commit in progress and commits it.
+func (g *Git) commitResolved(ctx context.Context, commit string) error {
+	// Rerere records the resolution of every file that no longer contains conflict markers.
+	if err := g.runCmd(ctx, "rerere"); err != nil {
+		return err
+	}
+	if err := g.runCmd(ctx, "add", "--all"); err != nil {
+		return err
+	}
+	// Forget about the cherry-pick in progress before committing, just like [Git.CherryPickWithMarkers] does.
+	if err := g.runCmd(ctx, "cherry-pick", "--quit"); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("Resolve conflicts of commit %s", commit)
+	return g.runCmd(ctx, "commit", "--no-verify", "--allow-empty", "--message", message)
+}
+
+// SaveRerere commits the recorded resolutions of the workspace onto the given ref and pushes it to the remote origin.
+//
+// The commit is based on the ref restored by [Git.RestoreRerere], if any, so that pushing it fails rather than
+// discarding resolutions recorded concurrently. Nothing is pushed if there are no new resolutions.
+func (g *Git) SaveRerere(ctx context.Context, ref string) error {
+	githubactions.Group(fmt.Sprintf("Saving recorded resolutions to %s", ref))
+	defer githubactions.EndGroup()
+
+	rrCache, err := g.rrCache(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(rrCache); errors.Is(err, fs.ErrNotExist) {
+		githubactions.Infof("No recorded resolutions to save")
+		return nil
+	}
+
+	// Build the tree of the recorded resolutions using a temporary index, leaving the one of the workspace as is.
+	tmp, err := os.MkdirTemp("", "backbot-rerere-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	index := *g
+	index.env = append(g.env[:len(g.env):len(g.env)], "GIT_INDEX_FILE="+filepath.Join(tmp, "index"), "GIT_WORK_TREE="+rrCache)
+	if err := index.runCmd(ctx, "add", "--all"); err != nil {
+		return err
+	}
+	tree, err := index.output(ctx, "write-tree")
+	if err != nil {
+		return err
+	}
+	tree = strings.TrimSpace(tree)
+
+	args := []string{"commit-tree", tree, "-m", "Update recorded resolutions"}
+	if parent, err := g.output(ctx, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+		parent = strings.TrimSpace(parent)
+		parentTree, err := g.output(ctx, "rev-parse", parent+"^{tree}")
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(parentTree) == tree {
+			githubactions.Infof("No new recorded resolutions to save")
+			return nil
+		}
+		args = append(args, "-p", parent)
+	}
+	commit, err := g.output(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	githubactions.Infof("Pushing recorded resolutions to %s", ref)
+	return g.runCmd(ctx, "push", "origin", fmt.Sprintf("%s:%s", strings.TrimSpace(commit), ref))
+}
+
+// rrCache returns the absolute path of the directory in which rerere records the resolutions.
+func (g *Git) rrCache(ctx context.Context) (string, error) {
+	path, err := g.output(ctx, "rev-parse", "--git-path", "rr-cache")
+	if err != nil {
+		return "", err
+	}
+	path = strings.TrimSpace(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(g.githubCtx.Workspace, path)
+	}
+	return path, nil
+}
@@ -0,0 +1,97 @@
+package github
+
+import (
+	"context"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// API is the set of GitHub operations backbot relies on to backport pull requests.
+//
+// It's implemented by [Client] against the GitHub REST and GraphQL APIs, and in memory by the Fake of the
+// githubtest package, which allows exercising the whole backport process in unit tests.
+type API interface {
+	// Repo returns the owner and name of the repository the backports are created in.
+	Repo() (string, string)
+
+	// WorkflowRunURL returns the URL of the workflow run backbot is running in, or an empty string if there's none.
+	WorkflowRunURL() string
+
+	// EventName returns the name of the event that triggered the workflow.
+	EventName() string
+
+	// EventAction returns the activity type of the event that triggered the workflow.
+	EventAction() string
+
+	// GetPrNumber returns the number of the pull request the triggering event refers to.
+	GetPrNumber() (int64, error)
+
+	// GetComment returns the body and author login of the comment of an issue_comment event.
+	GetComment() (string, string, error)
+
+	// GetEventLabel returns the name of the label of a labeled event.
+	GetEventLabel() (string, error)
+
+	// HasWriteAccess checks whether the given user has at least write access to the repository.
+	HasWriteAccess(ctx context.Context, user string) (bool, error)
+
+	// GetPR fetches a pull request by its number.
+	GetPR(ctx context.Context, prNumber int64) (*github.PullRequest, error)
+
+	// GetCommits fetches all commits associated with a pull request.
+	GetCommits(ctx context.Context, pr *github.PullRequest) ([]*github.RepositoryCommit, error)
+
+	// MergeKind returns the merge strategy used to merge the given pull request.
+	MergeKind(ctx context.Context, pr *github.PullRequest) (MergeKind, error)
+
+	// FindPR returns the open pull request from the given head branch into the given base branch, or nil if there's none.
+	FindPR(ctx context.Context, head, base string) (*github.PullRequest, error)
+
+	// CreatePR creates a new pull request in the repository.
+	CreatePR(ctx context.Context, pr *github.NewPullRequest) (*github.PullRequest, error)
+
+	// UpdatePR updates the title and body of the given pull request.
+	UpdatePR(ctx context.Context, pr *github.PullRequest, title, body string) error
+
+	// LabelPR adds the specified labels to a pull request.
+	LabelPR(ctx context.Context, pr *github.PullRequest, labels ...string) ([]*github.Label, error)
+
+	// RequestReviewers requests a review on the given pull request from the specified users and teams.
+	RequestReviewers(ctx context.Context, pr *github.PullRequest, reviewers ...string) error
+
+	// GetApprovers returns the logins of the users whose latest review of the given pull request approves it.
+	GetApprovers(ctx context.Context, pr *github.PullRequest) ([]string, error)
+
+	// AssignPR assigns the specified users to the given pull request.
+	AssignPR(ctx context.Context, pr *github.PullRequest, assignees ...string) error
+
+	// FindMilestone returns the open milestone with the given title, or nil if there's none.
+	FindMilestone(ctx context.Context, title string) (*github.Milestone, error)
+
+	// SetMilestone sets the milestone of the given pull request.
+	SetMilestone(ctx context.Context, pr *github.PullRequest, milestone *github.Milestone) error
+
+	// GetProjects returns the projects the given pull request has been added to.
+	GetProjects(ctx context.Context, pr *github.PullRequest) ([]Project, error)
+
+	// AddToProject adds the given pull request to the given project.
+	AddToProject(ctx context.Context, pr *github.PullRequest, project Project) error
+
+	// ConvertToDraft converts the given pull request to a draft.
+	ConvertToDraft(ctx context.Context, pr *github.PullRequest) error
+
+	// MarkReadyForReview marks the given draft pull request as ready for review.
+	MarkReadyForReview(ctx context.Context, pr *github.PullRequest) error
+
+	// EnableAutoMerge enables auto-merge with the given merge method on the given pull request.
+	EnableAutoMerge(ctx context.Context, pr *github.PullRequest, method string) error
+
+	// CreateComment adds a comment to the specified issue or pull request.
+	CreateComment(ctx context.Context, issueNumber int64, body string) error
+
+	// GetFileContents fetches the contents of a file from the default branch, or nil if it doesn't exist.
+	GetFileContents(ctx context.Context, path string) ([]byte, error)
+}
+
+// Ensure Client implements the API interface.
+var _ API = (*Client)(nil)
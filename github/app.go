@@ -0,0 +1,203 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// tokenRefreshMargin is the remaining lifetime below which an installation token is replaced by a new one.
+const tokenRefreshMargin = 5 * time.Minute
+
+// AppAuth authenticates backbot as the installation of a GitHub App on the repository of the GitHub context.
+//
+// It mints installation tokens on demand using JSON Web Tokens signed with the private key of the app, and
+// transparently replaces them shortly before they expire. AppAuth implements [http.RoundTripper], so it can
+// authenticate any HTTP client, and is safe for concurrent use.
+type AppAuth struct {
+	appID  int64
+	key    *rsa.PrivateKey
+	client *github.Client // GitHub API client authenticated as the app itself.
+	ghCtx  *githubactions.GitHubContext
+
+	mu             sync.Mutex
+	installationID int64
+	token          *github.InstallationToken
+}
+
+// NewAppAuth creates a new AppAuth for the GitHub App with the given ID and PEM encoded private key.
+//
+// Returns an error if the private key is invalid or the API URL of the GitHub context cannot be used.
+func NewAppAuth(ghCtx *githubactions.GitHubContext, appID int64, privateKey string) (*AppAuth, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, fmt.Errorf("private key of app %d is not PEM encoded", appID)
+	}
+
+	var key *rsa.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY": // The format of the keys generated by GitHub.
+		var err error
+		if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to parse private key of app %d: %w", appID, err)
+		}
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key of app %d: %w", appID, err)
+		}
+		var ok bool
+		if key, ok = parsed.(*rsa.PrivateKey); !ok {
+			return nil, fmt.Errorf("private key of app %d is not an RSA key", appID)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported private key type %q of app %d", block.Type, appID)
+	}
+
+	app := &AppAuth{appID: appID, key: key, ghCtx: ghCtx}
+	client, err := withEnterpriseURLs(ghCtx, github.NewClient(&http.Client{Transport: jwtTransport{app: app}}))
+	if err != nil {
+		return nil, err
+	}
+	app.client = client
+	return app, nil
+}
+
+// Token returns a valid installation token for the repository of the GitHub context.
+//
+// The installation of the app is looked up on first use, and a new token is minted whenever the current
+// one is about to expire. Every minted token is masked in the GitHub Actions logs.
+//
+// Returns the token or an error if the operation fails.
+func (a *AppAuth) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != nil && time.Until(a.token.GetExpiresAt().Time) > tokenRefreshMargin {
+		return a.token.GetToken(), nil
+	}
+
+	owner, repo := a.ghCtx.Repo()
+	if a.installationID == 0 {
+		githubactions.Infof("Looking up the installation of GitHub App %d for %s/%s", a.appID, owner, repo)
+
+		installation, resp, err := a.client.Apps.FindRepositoryInstallation(ctx, owner, repo)
+		if err != nil {
+			return "", fmt.Errorf("failed to find installation of app %d for %s/%s: %w", a.appID, owner, repo, err)
+		}
+		closeResponseBody(resp)
+		a.installationID = installation.GetID()
+	}
+
+	githubactions.Infof("Creating installation token for GitHub App %d", a.appID)
+	token, resp, err := a.client.Apps.CreateInstallationToken(ctx, a.installationID, &github.InstallationTokenOptions{
+		Repositories: []string{repo}, // Restrict the token to the repository backbot is operating on.
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create installation token for app %d: %w", a.appID, err)
+	}
+	closeResponseBody(resp)
+
+	githubactions.AddMask(token.GetToken())
+	a.token = token
+	return token.GetToken(), nil
+}
+
+// Revoke revokes the current installation token, if any, so that it cannot be used after backbot finished.
+//
+// Returns an error if the operation fails.
+func (a *AppAuth) Revoke(ctx context.Context) error {
+	a.mu.Lock()
+	token := a.token
+	a.token = nil
+	a.mu.Unlock()
+	if token == nil {
+		return nil
+	}
+
+	githubactions.Infof("Revoking installation token of GitHub App %d", a.appID)
+	client, err := withEnterpriseURLs(a.ghCtx, github.NewClient(nil).WithAuthToken(token.GetToken()))
+	if err != nil {
+		return err
+	}
+	resp, err := client.Apps.RevokeInstallationToken(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeResponseBody(resp)
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// RoundTrip authenticates the given request with an installation token and sends it.
+func (a *AppAuth) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := a.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// jwt creates a new JSON Web Token to authenticate as the app itself.
+//
+// The token is issued a minute in the past to allow for clock drift, and expires after the maximum
+// lifetime of ten minutes allowed by GitHub [^1].
+//
+// [^1]: https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-json-web-token-jwt-for-a-github-app
+func (a *AppAuth) jwt() (string, error) {
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT for app %d: %w", a.appID, err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// jwtTransport authenticates requests as the GitHub App itself rather than as one of its installations.
+type jwtTransport struct {
+	app *AppAuth
+}
+
+// RoundTrip authenticates the given request with a freshly signed JWT and sends it.
+func (t jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	jwt, err := t.app.jwt()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,123 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppAuth(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+
+	var mu sync.Mutex
+	var minted, revoked []string
+	lifetime := time.Hour
+
+	// requireJWT asserts that the request is authenticated as the app with ID 42.
+	requireJWT := func(r *http.Request) {
+		jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		require.True(t, ok)
+		parts := strings.Split(jwt, ".")
+		require.Len(t, parts, 3)
+
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		require.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, err)
+		var claims map[string]int64
+		require.NoError(t, json.Unmarshal(payload, &claims))
+		require.Equal(t, int64(42), claims["iss"])
+		require.Less(t, claims["iat"], time.Now().Unix())
+		require.Greater(t, claims["exp"], time.Now().Unix())
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/icinga/backbot/installation", func(w http.ResponseWriter, r *http.Request) {
+		requireJWT(r)
+		_ = json.NewEncoder(w).Encode(&github.Installation{ID: github.Ptr(int64(7))})
+	})
+	mux.HandleFunc("POST /api/v3/app/installations/7/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		requireJWT(r)
+		var opts github.InstallationTokenOptions
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&opts))
+		require.Equal(t, []string{"backbot"}, opts.Repositories)
+
+		mu.Lock()
+		defer mu.Unlock()
+		minted = append(minted, fmt.Sprintf("ghs_%d", len(minted)+1))
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(&github.InstallationToken{
+			Token:     github.Ptr(minted[len(minted)-1]),
+			ExpiresAt: &github.Timestamp{Time: time.Now().Add(lifetime)},
+		})
+	})
+	mux.HandleFunc("DELETE /api/v3/installation/token", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		revoked = append(revoked, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/v3/repos/icinga/backbot/pulls/1", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, "Bearer "+minted[len(minted)-1], r.Header.Get("Authorization"))
+		_ = json.NewEncoder(w).Encode(&github.PullRequest{Number: github.Ptr(1)})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ghCtx := &githubactions.GitHubContext{APIURL: srv.URL + "/api/v3", Repository: "icinga/backbot"}
+	app, err := NewAppAuth(ghCtx, 42, privateKey)
+	require.NoError(t, err)
+	client, err := NewAppClient(ghCtx, app)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for range 2 {
+		_, err := client.GetPR(ctx, 1)
+		require.NoError(t, err)
+	}
+	require.Equal(t, []string{"ghs_1"}, minted, "valid tokens must be reused")
+
+	token, err := app.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "ghs_1", token)
+
+	mu.Lock()
+	lifetime = time.Minute
+	mu.Unlock()
+	app.token.ExpiresAt = &github.Timestamp{Time: time.Now().Add(time.Minute)}
+	_, err = client.GetPR(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ghs_1", "ghs_2"}, minted, "tokens about to expire must be refreshed")
+
+	require.NoError(t, app.Revoke(ctx))
+	require.Equal(t, []string{"ghs_2"}, revoked)
+	require.NoError(t, app.Revoke(ctx), "revoking twice must be a no-op")
+	require.Len(t, revoked, 1)
+
+	_, err = NewAppAuth(ghCtx, 42, "not a key")
+	require.Error(t, err)
+}
@@ -5,54 +5,223 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/google/go-github/v75/github"
 	"github.com/sethvargo/go-githubactions"
 )
 
+// The names of the events that can trigger a backport.
+const (
+	EventPullRequest       = "pull_request"
+	EventPullRequestTarget = "pull_request_target"
+	EventIssueComment      = "issue_comment"
+)
+
 // Client wraps the GitHub client to provide methods for interacting with GitHub API.
 type Client struct {
-	client    *github.Client               // GitHub API client
-	githubCtx *githubactions.GitHubContext // GitHub Actions context
+	client     *github.Client               // GitHub API client
+	githubCtx  *githubactions.GitHubContext // GitHub Actions context
+	graphQLURL string                       // URL of the GraphQL API, derived from the API URL of the GitHub context
 
 	commitsPRCache map[int64][]*github.RepositoryCommit // Cache for commits in PRs to avoid redundant API calls.
 }
 
+// defaultAPIURL is the URL of the REST API of github.com.
+const defaultAPIURL = "https://api.github.com"
+
 // NewClient creates a new GitHub client with the provided authentication token.
-func NewClient(ghCtx *githubactions.GitHubContext, githubToken string) *Client {
+//
+// The client talks to the REST API at the API URL of the GitHub context, see [withEnterpriseURLs].
+// Returns an error if the API URL of the GitHub context is invalid.
+func NewClient(ghCtx *githubactions.GitHubContext, githubToken string) (*Client, error) {
+	return newClient(ghCtx, github.NewClient(nil).WithAuthToken(githubToken))
+}
+
+// NewAppClient creates a new GitHub client authenticated as the installation of a GitHub App.
+//
+// The client talks to the REST API at the API URL of the GitHub context, see [withEnterpriseURLs].
+// Returns an error if the API URL of the GitHub context is invalid.
+func NewAppClient(ghCtx *githubactions.GitHubContext, app *AppAuth) (*Client, error) {
+	return newClient(ghCtx, github.NewClient(&http.Client{Transport: app}))
+}
+
+// newClient wraps the given GitHub API client after pointing it to the API URL of the GitHub context.
+func newClient(ghCtx *githubactions.GitHubContext, client *github.Client) (*Client, error) {
+	client, err := withEnterpriseURLs(ghCtx, client)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Client{
-		client:         github.NewClient(nil).WithAuthToken(githubToken),
+		client:         client,
 		githubCtx:      ghCtx,
+		graphQLURL:     graphQLURL(ghCtx),
 		commitsPRCache: make(map[int64][]*github.RepositoryCommit),
+	}, nil
+}
+
+// withEnterpriseURLs points the given GitHub API client to the API URL of the GitHub context.
+//
+// The API URL is set by the runner to the GitHub instance the workflow runs on. Any URL other than the one of
+// github.com is treated as a GitHub Enterprise Server instance, with uploads going to its /api/uploads endpoint.
+//
+// Returns the reconfigured client or an error if the API URL is invalid.
+func withEnterpriseURLs(ghCtx *githubactions.GitHubContext, client *github.Client) (*github.Client, error) {
+	apiURL := strings.TrimSuffix(ghCtx.APIURL, "/")
+	if apiURL == "" || apiURL == defaultAPIURL {
+		return client, nil
+	}
+
+	uploadURL := strings.TrimSuffix(apiURL, "/api/v3") + "/api/uploads/"
+	client, err := client.WithEnterpriseURLs(apiURL, uploadURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub API URL %q: %w", ghCtx.APIURL, err)
+	}
+	return client, nil
+}
+
+// graphQLURL returns the URL of the GraphQL API of the GitHub instance the API URL of the GitHub context points to.
+//
+// The GraphQL URL of the GitHub context is ignored, as it isn't updated when the API URL is overridden. GitHub
+// Enterprise Server serves its REST API under /api/v3 and its GraphQL API under /api/graphql, any other API URL
+// gets /graphql appended, as on github.com.
+func graphQLURL(ghCtx *githubactions.GitHubContext) string {
+	apiURL := strings.TrimSuffix(ghCtx.APIURL, "/")
+	if apiURL == "" || apiURL == defaultAPIURL {
+		return defaultGraphQLURL
+	}
+	if serverURL, ok := strings.CutSuffix(apiURL, "/api/v3"); ok {
+		return serverURL + "/api/graphql"
 	}
+	return apiURL + "/graphql"
 }
 
 // Repo fetches the owner and repository name from the GitHub context.
 func (c *Client) Repo() (string, string) { return c.githubCtx.Repo() }
 
+// WorkflowRunURL returns the URL of the workflow run backbot is running in on the GitHub server.
+//
+// Returns an empty string if backbot isn't running in a GitHub Actions workflow, e.g. when run locally.
+func (c *Client) WorkflowRunURL() string {
+	if c.githubCtx.RunID == 0 || c.githubCtx.ServerURL == "" {
+		return ""
+	}
+	owner, repo := c.Repo()
+	return fmt.Sprintf("%s/%s/%s/actions/runs/%d", strings.TrimSuffix(c.githubCtx.ServerURL, "/"), owner, repo, c.githubCtx.RunID)
+}
+
+// EventName returns the name of the event that triggered the workflow.
+func (c *Client) EventName() string { return c.githubCtx.EventName }
+
+// EventAction returns the activity type of the event that triggered the workflow, e.g. "closed" or "created".
+//
+// Returns an empty string if the event payload doesn't contain an action.
+func (c *Client) EventAction() string {
+	action, err := c.eventField("action")
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprint(action)
+}
+
 // GetPrNumber fetches the pull request number from the event context.
 //
+// For issue_comment events, the number of the commented issue is returned, as long as the issue is
+// actually a pull request.
+//
 // Returns the pull request number or an error if the operation fails.
 func (c *Client) GetPrNumber() (int64, error) {
-	if c.githubCtx.EventName != "pull_request" {
+	var number any
+	var err error
+	switch c.githubCtx.EventName {
+	case EventPullRequest, EventPullRequestTarget:
+		number, err = c.eventField("pull_request", "number")
+	case EventIssueComment:
+		if _, err := c.eventField("issue", "pull_request"); err != nil {
+			return 0, fmt.Errorf("commented issue is not a pull request: %w", err)
+		}
+		number, err = c.eventField("issue", "number")
+	default:
 		return 0, fmt.Errorf("event is not a pull request")
 	}
-	if c.githubCtx.Event == nil {
-		return 0, fmt.Errorf("event payload is nil")
+	if err != nil {
+		return 0, err
 	}
-	pr, ok := c.githubCtx.Event["pull_request"]
-	if !ok {
-		return 0, fmt.Errorf("pull_request field not found in event payload")
+	return strconv.ParseInt(fmt.Sprint(number), 10, 64)
+}
+
+// GetComment fetches the body and author login of the comment from an issue_comment event.
+//
+// Returns the comment body, the author login or an error if the operation fails.
+func (c *Client) GetComment() (string, string, error) {
+	if c.githubCtx.EventName != EventIssueComment {
+		return "", "", fmt.Errorf("event is not an issue comment")
 	}
-	prMap, ok := pr.(map[string]any)
-	if !ok {
-		return 0, fmt.Errorf("pull_request field is not a map")
+	body, err := c.eventField("comment", "body")
+	if err != nil {
+		return "", "", err
 	}
-	number, ok := prMap["number"]
-	if !ok {
-		return 0, fmt.Errorf("pull_request.number field not found in event payload")
+	author, err := c.eventField("comment", "user", "login")
+	if err != nil {
+		return "", "", err
 	}
-	return strconv.ParseInt(fmt.Sprint(number), 10, 64)
+	return fmt.Sprint(body), fmt.Sprint(author), nil
+}
+
+// GetEventLabel fetches the name of the label that was added or removed in a labeled/unlabeled event.
+//
+// Returns the label name or an error if the operation fails.
+func (c *Client) GetEventLabel() (string, error) {
+	name, err := c.eventField("label", "name")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(name), nil
+}
+
+// HasWriteAccess checks whether the given user has at least write access to the repository.
+//
+// Returns true if the user is allowed to push to the repository, false otherwise.
+func (c *Client) HasWriteAccess(ctx context.Context, user string) (bool, error) {
+	owner, repo := c.Repo()
+	githubactions.Infof("Retrieving permission level of user %s for %s/%s", user, owner, repo)
+
+	level, resp, err := c.client.Repositories.GetPermissionLevel(ctx, owner, repo, user)
+	if err != nil {
+		return false, err
+	}
+	defer closeResponseBody(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	switch level.GetPermission() {
+	case "admin", "maintain", "write":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// eventField looks up the (nested) field identified by the given path in the event payload.
+//
+// Returns the field value or an error if any of the path elements doesn't exist.
+func (c *Client) eventField(path ...string) (any, error) {
+	if c.githubCtx.Event == nil {
+		return nil, fmt.Errorf("event payload is nil")
+	}
+	var value any = c.githubCtx.Event
+	for i, key := range path {
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s field is not a map", strings.Join(path[:i], "."))
+		}
+		if value, ok = fields[key]; !ok || value == nil {
+			return nil, fmt.Errorf("%s field not found in event payload", strings.Join(path[:i+1], "."))
+		}
+	}
+	return value, nil
 }
 
 // GetPR fetches a pull request by its number.
@@ -76,6 +245,32 @@ func (c *Client) GetPR(ctx context.Context, prNumber int64) (*github.PullRequest
 	return pr, nil
 }
 
+// FindPR finds the open pull request from the given head branch of the repository into the given base branch.
+//
+// Returns nil if there's no such pull request, or an error if the operation fails.
+func (c *Client) FindPR(ctx context.Context, head, base string) (*github.PullRequest, error) {
+	owner, repo := c.Repo()
+	githubactions.Infof("Looking for an open PR from %s to %s in %s/%s", head, base, owner, repo)
+
+	prs, resp, err := c.client.PullRequests.List(ctx, owner, repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  fmt.Sprintf("%s:%s", owner, head),
+		Base:  base,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer closeResponseBody(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return prs[0], nil
+}
+
 // CreatePR creates a new pull request in the repository.
 //
 // Returns the created pull request object or an error if the operation fails.
@@ -95,6 +290,28 @@ func (c *Client) CreatePR(ctx context.Context, pr *github.NewPullRequest) (*gith
 	return createdPr, nil
 }
 
+// UpdatePR updates the title and body of the given pull request.
+//
+// Returns an error if the operation fails.
+func (c *Client) UpdatePR(ctx context.Context, pr *github.PullRequest, title, body string) error {
+	owner, repo := c.Repo()
+	githubactions.Infof("Updating title and body of PR #%d in %s/%s", pr.GetNumber(), owner, repo)
+
+	_, resp, err := c.client.PullRequests.Edit(ctx, owner, repo, pr.GetNumber(), &github.PullRequest{
+		Title: github.Ptr(title),
+		Body:  github.Ptr(body),
+	})
+	if err != nil {
+		return err
+	}
+	defer closeResponseBody(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // LabelPR adds the specified labels to a pull request.
 //
 // Returns the added labels or an error if the operation fails.
@@ -103,7 +320,7 @@ func (c *Client) LabelPR(ctx context.Context, pr *github.PullRequest, labels ...
 		return nil, nil
 	}
 
-	repo, owner := c.Repo()
+	owner, repo := c.Repo()
 	githubactions.Infof("Adding labels '%+v' to PR #%d in %s/%s", labels, pr.GetNumber(), owner, repo)
 
 	ghLabels, resp, err := c.client.Issues.AddLabelsToIssue(ctx, owner, repo, pr.GetNumber(), labels)
@@ -118,6 +335,188 @@ func (c *Client) LabelPR(ctx context.Context, pr *github.PullRequest, labels ...
 	return ghLabels, nil
 }
 
+// RequestReviewers requests a review on the given pull request from the specified users and teams.
+//
+// Reviewers in the form of "org/team-slug" are treated as teams, all others as users.
+// Returns an error if the operation fails.
+func (c *Client) RequestReviewers(ctx context.Context, pr *github.PullRequest, reviewers ...string) error {
+	if len(reviewers) == 0 {
+		return nil
+	}
+
+	owner, repo := c.Repo()
+	githubactions.Infof("Requesting review from '%+v' on PR #%d in %s/%s", reviewers, pr.GetNumber(), owner, repo)
+
+	var request github.ReviewersRequest
+	for _, reviewer := range reviewers {
+		if _, team, ok := strings.Cut(reviewer, "/"); ok {
+			request.TeamReviewers = append(request.TeamReviewers, team)
+		} else {
+			request.Reviewers = append(request.Reviewers, reviewer)
+		}
+	}
+
+	_, resp, err := c.client.PullRequests.RequestReviewers(ctx, owner, repo, pr.GetNumber(), request)
+	if err != nil {
+		return err
+	}
+	defer closeResponseBody(resp)
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GetApprovers returns the logins of the users whose latest review of the given pull request approves it.
+//
+// Reviews that merely comment on the pull request don't change the state of previous reviews, while the approvals
+// that were dismissed or followed by a request for changes don't count. Reviews of bots, which cannot be requested
+// to review, are ignored. Returns an error if the operation fails.
+func (c *Client) GetApprovers(ctx context.Context, pr *github.PullRequest) ([]string, error) {
+	owner, repo := c.Repo()
+	githubactions.Infof("Retrieving approvals of PR #%d in %s/%s", pr.GetNumber(), owner, repo)
+
+	var users []string
+	states := make(map[string]string)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		reviews, resp, err := c.client.PullRequests.ListReviews(ctx, owner, repo, pr.GetNumber(), opts)
+		if err != nil {
+			return nil, err
+		}
+		closeResponseBody(resp)
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+		for _, review := range reviews { // The reviews are listed in chronological order.
+			login, state := review.GetUser().GetLogin(), review.GetState()
+			if login == "" || review.GetUser().GetType() == "Bot" || state == "COMMENTED" || state == "PENDING" {
+				continue
+			}
+			if _, ok := states[login]; !ok {
+				users = append(users, login)
+			}
+			states[login] = state
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	var approvers []string
+	for _, user := range users {
+		if states[user] == "APPROVED" {
+			approvers = append(approvers, user)
+		}
+	}
+	return approvers, nil
+}
+
+// AssignPR assigns the specified users to the given pull request.
+//
+// Returns an error if the operation fails.
+func (c *Client) AssignPR(ctx context.Context, pr *github.PullRequest, assignees ...string) error {
+	if len(assignees) == 0 {
+		return nil
+	}
+
+	owner, repo := c.Repo()
+	githubactions.Infof("Assigning '%+v' to PR #%d in %s/%s", assignees, pr.GetNumber(), owner, repo)
+
+	_, resp, err := c.client.Issues.AddAssignees(ctx, owner, repo, pr.GetNumber(), assignees)
+	if err != nil {
+		return err
+	}
+	defer closeResponseBody(resp)
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// FindMilestone finds the open milestone with the given title in the repository.
+//
+// Returns nil if there's no such milestone, or an error if the operation fails.
+func (c *Client) FindMilestone(ctx context.Context, title string) (*github.Milestone, error) {
+	owner, repo := c.Repo()
+	githubactions.Infof("Looking for milestone %q in %s/%s", title, owner, repo)
+
+	opts := &github.MilestoneListOptions{State: "open", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		milestones, resp, err := c.client.Issues.ListMilestones(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		closeResponseBody(resp)
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+		for _, milestone := range milestones {
+			if milestone.GetTitle() == title {
+				return milestone, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// SetMilestone sets the milestone of the given pull request.
+//
+// Returns an error if the operation fails.
+func (c *Client) SetMilestone(ctx context.Context, pr *github.PullRequest, milestone *github.Milestone) error {
+	owner, repo := c.Repo()
+	githubactions.Infof("Setting milestone %q of PR #%d in %s/%s", milestone.GetTitle(), pr.GetNumber(), owner, repo)
+
+	_, resp, err := c.client.Issues.Edit(ctx, owner, repo, pr.GetNumber(), &github.IssueRequest{Milestone: milestone.Number})
+	if err != nil {
+		return err
+	}
+	defer closeResponseBody(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GetFileContents fetches the contents of the file at the given path from the default branch of the repository.
+//
+// Returns the file contents, nil if the file does not exist, or an error if the operation fails.
+func (c *Client) GetFileContents(ctx context.Context, path string) ([]byte, error) {
+	owner, repo := c.Repo()
+	githubactions.Infof("Retrieving file %s from %s/%s", path, owner, repo)
+
+	file, _, resp, err := c.client.Repositories.GetContents(ctx, owner, repo, path, nil)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		closeResponseBody(resp)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closeResponseBody(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
 // ListFiles lists the files changed in the specified pull request.
 //
 // It will iteratively fetch files in pages until all files are retrieved.
@@ -0,0 +1,377 @@
+// Package githubtest provides an in-memory implementation of the GitHub operations used by backbot.
+package githubtest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/google/go-github/v75/github"
+	bbgithub "github.com/yhabteab/backbot/github"
+)
+
+// Fake is an in-memory implementation of [bbgithub.API] for tests.
+//
+// The event that triggered the workflow is described by the exported event fields, while the repository
+// state is populated via [Fake.AddPR] and the other exported fields. Everything backbot creates, such as
+// pull requests, comments, labels, review requests, assignees, milestones, project items and auto-merge, is recorded and can be inspected afterward.
+// It's safe for concurrent use as long as the exported fields aren't modified concurrently.
+type Fake struct {
+	Owner, Name string // The owner and name of the repository.
+	RunURL      string // The URL of the workflow run backbot is running in.
+
+	Event         string // The name of the triggering event, e.g. "pull_request".
+	Action        string // The activity type of the triggering event, e.g. "closed".
+	PrNumber      int64  // The number of the pull request the event refers to.
+	CommentBody   string // The comment body of an issue_comment event.
+	CommentAuthor string // The comment author of an issue_comment event.
+	Label         string // The label of a labeled event.
+
+	Writers    []string                     // The users with write access to the repository.
+	Files      map[string][]byte            // The files on the default branch, keyed by their path.
+	Approvers  map[int64][]string           // The users who approved each pull request, keyed by its number.
+	Milestones []*github.Milestone          // The open milestones of the repository.
+	Projects   map[int64][]bbgithub.Project // The projects each pull request has been added to, keyed by its number.
+
+	mu         sync.Mutex
+	prs        map[int64]*github.PullRequest
+	commits    map[int64][]*github.RepositoryCommit
+	mergeKinds map[int64]bbgithub.MergeKind
+	comments   map[int64][]string
+	labels     map[int64][]string
+	reviewers  map[int64][]string
+	assignees  map[int64][]string
+	autoMerge  map[int64]string
+	created    []*github.PullRequest
+}
+
+// Ensure Fake implements the API interface.
+var _ bbgithub.API = (*Fake)(nil)
+
+// NewFake creates a new Fake for the given repository without any pull requests.
+func NewFake(owner, name string) *Fake {
+	return &Fake{
+		Owner:      owner,
+		Name:       name,
+		Files:      make(map[string][]byte),
+		Approvers:  make(map[int64][]string),
+		Projects:   make(map[int64][]bbgithub.Project),
+		prs:        make(map[int64]*github.PullRequest),
+		commits:    make(map[int64][]*github.RepositoryCommit),
+		mergeKinds: make(map[int64]bbgithub.MergeKind),
+		comments:   make(map[int64][]string),
+		labels:     make(map[int64][]string),
+		reviewers:  make(map[int64][]string),
+		assignees:  make(map[int64][]string),
+		autoMerge:  make(map[int64]string),
+	}
+}
+
+// AddPR adds an existing pull request with its commits, merged with the given merge kind.
+func (f *Fake) AddPR(pr *github.PullRequest, mk bbgithub.MergeKind, commits ...*github.RepositoryCommit) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	number := int64(pr.GetNumber())
+	f.prs[number] = pr
+	f.commits[number] = commits
+	f.mergeKinds[number] = mk
+}
+
+// CreatedPRs returns all the pull requests created via [Fake.CreatePR] in creation order.
+func (f *Fake) CreatedPRs() []*github.PullRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.created)
+}
+
+// Comments returns the bodies of all comments created on the given issue or pull request.
+func (f *Fake) Comments(issueNumber int64) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.comments[issueNumber])
+}
+
+// Labels returns all labels added to the given pull request.
+func (f *Fake) Labels(prNumber int64) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.labels[prNumber])
+}
+
+// Reviewers returns all reviewers requested for the given pull request.
+func (f *Fake) Reviewers(prNumber int64) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.reviewers[prNumber])
+}
+
+// Assignees returns the users assigned to the given pull request.
+func (f *Fake) Assignees(prNumber int64) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.assignees[prNumber])
+}
+
+// AutoMergeMethod returns the merge method auto-merge was enabled with on the given pull request, if any.
+func (f *Fake) AutoMergeMethod(prNumber int64) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.autoMerge[prNumber]
+}
+
+// The remaining methods implement [bbgithub.API], see its documentation for details.
+
+func (f *Fake) Repo() (string, string) { return f.Owner, f.Name }
+
+func (f *Fake) WorkflowRunURL() string { return f.RunURL }
+
+func (f *Fake) EventName() string { return f.Event }
+
+func (f *Fake) EventAction() string { return f.Action }
+
+func (f *Fake) GetPrNumber() (int64, error) {
+	if f.PrNumber == 0 {
+		return 0, fmt.Errorf("event does not refer to a pull request")
+	}
+	return f.PrNumber, nil
+}
+
+func (f *Fake) GetComment() (string, string, error) {
+	if f.Event != bbgithub.EventIssueComment {
+		return "", "", fmt.Errorf("event is not an issue comment")
+	}
+	return f.CommentBody, f.CommentAuthor, nil
+}
+
+func (f *Fake) GetEventLabel() (string, error) {
+	if f.Label == "" {
+		return "", fmt.Errorf("label field not found in event payload")
+	}
+	return f.Label, nil
+}
+
+func (f *Fake) HasWriteAccess(_ context.Context, user string) (bool, error) {
+	return slices.Contains(f.Writers, user), nil
+}
+
+func (f *Fake) GetPR(_ context.Context, prNumber int64) (*github.PullRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pr, ok := f.prs[prNumber]
+	if !ok {
+		return nil, fmt.Errorf("pull request #%d not found", prNumber)
+	}
+	return pr, nil
+}
+
+func (f *Fake) GetCommits(_ context.Context, pr *github.PullRequest) ([]*github.RepositoryCommit, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.commits[int64(pr.GetNumber())], nil
+}
+
+func (f *Fake) MergeKind(_ context.Context, pr *github.PullRequest) (bbgithub.MergeKind, error) {
+	if !pr.GetMerged() {
+		return bbgithub.MergeInvalid, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.mergeKinds[int64(pr.GetNumber())], nil
+}
+
+func (f *Fake) FindPR(_ context.Context, head, base string) (*github.PullRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var found *github.PullRequest
+	for _, pr := range f.prs {
+		if pr.GetState() == "open" && pr.GetHead().GetRef() == head && pr.GetBase().GetRef() == base {
+			if found == nil || pr.GetNumber() < found.GetNumber() {
+				found = pr
+			}
+		}
+	}
+	return found, nil
+}
+
+func (f *Fake) CreatePR(_ context.Context, newPr *github.NewPullRequest) (*github.PullRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, pr := range f.prs {
+		if pr.GetState() == "open" && pr.GetHead().GetRef() == newPr.GetHead() && pr.GetBase().GetRef() == newPr.GetBase() {
+			return nil, fmt.Errorf("a pull request already exists for %s:%s", f.Owner, newPr.GetHead())
+		}
+	}
+
+	number := len(f.prs) + 1
+	for f.prs[int64(number)] != nil {
+		number++
+	}
+	pr := &github.PullRequest{
+		Number:              github.Ptr(number),
+		State:               github.Ptr("open"),
+		Title:               newPr.Title,
+		Body:                newPr.Body,
+		Draft:               github.Ptr(newPr.GetDraft()),
+		MaintainerCanModify: newPr.MaintainerCanModify,
+		Head:                &github.PullRequestBranch{Ref: newPr.Head},
+		Base:                &github.PullRequestBranch{Ref: newPr.Base},
+		HTMLURL:             github.Ptr(fmt.Sprintf("https://github.com/%s/%s/pull/%d", f.Owner, f.Name, number)),
+	}
+	f.prs[int64(number)] = pr
+	f.created = append(f.created, pr)
+	return pr, nil
+}
+
+func (f *Fake) UpdatePR(_ context.Context, pr *github.PullRequest, title, body string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored, ok := f.prs[int64(pr.GetNumber())]
+	if !ok {
+		return fmt.Errorf("pull request #%d not found", pr.GetNumber())
+	}
+	stored.Title, stored.Body = github.Ptr(title), github.Ptr(body)
+	return nil
+}
+
+func (f *Fake) LabelPR(_ context.Context, pr *github.PullRequest, labels ...string) ([]*github.Label, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	number := int64(pr.GetNumber())
+	f.labels[number] = append(f.labels[number], labels...)
+
+	var ghLabels []*github.Label
+	for _, label := range f.labels[number] {
+		ghLabels = append(ghLabels, &github.Label{Name: github.Ptr(label)})
+	}
+	return ghLabels, nil
+}
+
+func (f *Fake) RequestReviewers(_ context.Context, pr *github.PullRequest, reviewers ...string) error {
+	if len(reviewers) == 0 {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	number := int64(pr.GetNumber())
+	f.reviewers[number] = append(f.reviewers[number], reviewers...)
+	return nil
+}
+
+func (f *Fake) GetApprovers(_ context.Context, pr *github.PullRequest) ([]string, error) {
+	return f.Approvers[int64(pr.GetNumber())], nil
+}
+
+func (f *Fake) AssignPR(_ context.Context, pr *github.PullRequest, assignees ...string) error {
+	if len(assignees) == 0 {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	number := int64(pr.GetNumber())
+	f.assignees[number] = append(f.assignees[number], assignees...)
+	return nil
+}
+
+func (f *Fake) FindMilestone(_ context.Context, title string) (*github.Milestone, error) {
+	for _, milestone := range f.Milestones {
+		if milestone.GetTitle() == title {
+			return milestone, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *Fake) SetMilestone(_ context.Context, pr *github.PullRequest, milestone *github.Milestone) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored, ok := f.prs[int64(pr.GetNumber())]
+	if !ok {
+		return fmt.Errorf("pull request #%d not found", pr.GetNumber())
+	}
+	stored.Milestone = milestone
+	return nil
+}
+
+func (f *Fake) GetProjects(_ context.Context, pr *github.PullRequest) ([]bbgithub.Project, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.Projects[int64(pr.GetNumber())]), nil
+}
+
+func (f *Fake) AddToProject(_ context.Context, pr *github.PullRequest, project bbgithub.Project) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	number := int64(pr.GetNumber())
+	f.Projects[number] = append(f.Projects[number], project)
+	return nil
+}
+
+func (f *Fake) ConvertToDraft(_ context.Context, pr *github.PullRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored, ok := f.prs[int64(pr.GetNumber())]
+	if !ok {
+		return fmt.Errorf("pull request #%d not found", pr.GetNumber())
+	}
+	stored.Draft = github.Ptr(true)
+	delete(f.autoMerge, int64(pr.GetNumber()))
+	return nil
+}
+
+func (f *Fake) MarkReadyForReview(_ context.Context, pr *github.PullRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored, ok := f.prs[int64(pr.GetNumber())]
+	if !ok {
+		return fmt.Errorf("pull request #%d not found", pr.GetNumber())
+	}
+	stored.Draft = github.Ptr(false)
+	return nil
+}
+
+func (f *Fake) EnableAutoMerge(_ context.Context, pr *github.PullRequest, method string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored, ok := f.prs[int64(pr.GetNumber())]
+	if !ok {
+		return fmt.Errorf("pull request #%d not found", pr.GetNumber())
+	}
+	if stored.GetDraft() {
+		return fmt.Errorf("pull request #%d is a draft", pr.GetNumber())
+	}
+	f.autoMerge[int64(pr.GetNumber())] = method
+	return nil
+}
+
+func (f *Fake) CreateComment(_ context.Context, issueNumber int64, body string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.comments[issueNumber] = append(f.comments[issueNumber], body)
+	return nil
+}
+
+func (f *Fake) GetFileContents(_ context.Context, path string) ([]byte, error) {
+	return f.Files[path], nil
+}
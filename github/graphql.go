@@ -0,0 +1,156 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// defaultGraphQLURL is the URL of the GraphQL API of github.com.
+const defaultGraphQLURL = "https://api.github.com/graphql"
+
+// Project is a GitHub project (v2) an issue or pull request can be added to.
+type Project struct {
+	ID    string // The GraphQL node ID of the project.
+	Title string
+}
+
+// graphQLRequest is the body of a request to the GraphQL API.
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+// graphQLResponse is the body of a response of the GraphQL API, with the data decoded into the given type.
+type graphQLResponse[T any] struct {
+	Data   T `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// graphQL sends the given query or mutation with the given variables to the GraphQL API of the GitHub instance.
+//
+// Some features, such as projects and auto-merge, are only available via the GraphQL API. The GraphQL API URL is
+// derived from the API URL of the client, see [graphQLURL]. Returns the data of the response, or an error if the
+// request fails or the response reports any errors.
+func graphQL[T any](ctx context.Context, c *Client, query string, variables map[string]any) (T, error) {
+	var data T
+	req, err := c.client.NewRequest(http.MethodPost, c.graphQLURL, &graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return data, err
+	}
+
+	var res graphQLResponse[T]
+	resp, err := c.client.Do(ctx, req, &res)
+	if err != nil {
+		return data, err
+	}
+	defer closeResponseBody(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return data, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if len(res.Errors) != 0 {
+		var msgs []string
+		for _, e := range res.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return data, errors.New(strings.Join(msgs, "; "))
+	}
+	return res.Data, nil
+}
+
+// GetProjects returns the projects the given pull request has been added to.
+//
+// Returns an error if the operation fails, e.g. because the token isn't allowed to read projects.
+func (c *Client) GetProjects(ctx context.Context, pr *github.PullRequest) ([]Project, error) {
+	owner, repo := c.Repo()
+	githubactions.Infof("Retrieving projects of PR #%d in %s/%s", pr.GetNumber(), owner, repo)
+
+	data, err := graphQL[struct {
+		Repository struct {
+			PullRequest struct {
+				ProjectItems struct {
+					Nodes []struct {
+						Project Project `json:"project"`
+					} `json:"nodes"`
+				} `json:"projectItems"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	}](ctx, c, `query($owner: String!, $repo: String!, $number: Int!) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      projectItems(first: 100) { nodes { project { id title } } }
+    }
+  }
+}`, map[string]any{"owner": owner, "repo": repo, "number": pr.GetNumber()})
+	if err != nil {
+		return nil, err
+	}
+
+	var projects []Project
+	for _, node := range data.Repository.PullRequest.ProjectItems.Nodes {
+		projects = append(projects, node.Project)
+	}
+	return projects, nil
+}
+
+// AddToProject adds the given pull request to the given project.
+//
+// Returns an error if the operation fails, e.g. because the token isn't allowed to write to the project.
+func (c *Client) AddToProject(ctx context.Context, pr *github.PullRequest, project Project) error {
+	owner, repo := c.Repo()
+	githubactions.Infof("Adding PR #%d in %s/%s to project %q", pr.GetNumber(), owner, repo, project.Title)
+
+	_, err := graphQL[struct{}](ctx, c, `mutation($project: ID!, $content: ID!) {
+  addProjectV2ItemById(input: {projectId: $project, contentId: $content}) { item { id } }
+}`, map[string]any{"project": project.ID, "content": pr.GetNodeID()})
+	return err
+}
+
+// ConvertToDraft converts the given pull request to a draft, which also disables auto-merge on it.
+//
+// Returns an error if the operation fails, e.g. because the pull request is closed.
+func (c *Client) ConvertToDraft(ctx context.Context, pr *github.PullRequest) error {
+	owner, repo := c.Repo()
+	githubactions.Infof("Converting PR #%d in %s/%s to a draft", pr.GetNumber(), owner, repo)
+
+	_, err := graphQL[struct{}](ctx, c, `mutation($pr: ID!) {
+  convertPullRequestToDraft(input: {pullRequestId: $pr}) { pullRequest { isDraft } }
+}`, map[string]any{"pr": pr.GetNodeID()})
+	return err
+}
+
+// MarkReadyForReview marks the given draft pull request as ready for review.
+//
+// Returns an error if the operation fails, e.g. because the pull request is closed.
+func (c *Client) MarkReadyForReview(ctx context.Context, pr *github.PullRequest) error {
+	owner, repo := c.Repo()
+	githubactions.Infof("Marking PR #%d in %s/%s as ready for review", pr.GetNumber(), owner, repo)
+
+	_, err := graphQL[struct{}](ctx, c, `mutation($pr: ID!) {
+  markPullRequestReadyForReview(input: {pullRequestId: $pr}) { pullRequest { isDraft } }
+}`, map[string]any{"pr": pr.GetNodeID()})
+	return err
+}
+
+// EnableAutoMerge enables auto-merge on the given pull request, so that GitHub merges it with the given merge method,
+// "merge", "squash" or "rebase", once all its requirements, such as passing status checks, are met.
+//
+// Returns an error if the operation fails, e.g. because auto-merge isn't allowed in the repository or the
+// pull request is a draft.
+func (c *Client) EnableAutoMerge(ctx context.Context, pr *github.PullRequest, method string) error {
+	owner, repo := c.Repo()
+	githubactions.Infof("Enabling auto-merge (%s) on PR #%d in %s/%s", method, pr.GetNumber(), owner, repo)
+
+	_, err := graphQL[struct{}](ctx, c, `mutation($pr: ID!, $method: PullRequestMergeMethod!) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $pr, mergeMethod: $method}) { clientMutationId }
+}`, map[string]any{"pr": pr.GetNodeID(), "method": strings.ToUpper(method)})
+	return err
+}
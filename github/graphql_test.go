@@ -0,0 +1,65 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQL(t *testing.T) {
+	var mutations []map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		var req graphQLRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		if _, ok := req.Variables["method"]; ok {
+			mutations = append(mutations, req.Variables)
+			_, _ = w.Write([]byte(`{"data": {"enablePullRequestAutoMerge": {"clientMutationId": null}}}`))
+			return
+		}
+		switch req.Variables["content"] {
+		case nil:
+			require.Equal(t, map[string]any{"owner": "icinga", "repo": "backbot", "number": float64(1)}, req.Variables)
+			_, _ = w.Write([]byte(`{"data": {"repository": {"pullRequest": {"projectItems": {"nodes": [
+				{"project": {"id": "PVT_1", "title": "Releases"}}, {"project": {"id": "PVT_2", "title": "Roadmap"}}
+			]}}}}}`))
+		case "PR_2":
+			mutations = append(mutations, req.Variables)
+			_, _ = w.Write([]byte(`{"data": {"addProjectV2ItemById": {"item": {"id": "PVTI_1"}}}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data": null, "errors": [{"message": "Could not resolve to a node"}]}`))
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ghCtx := &githubactions.GitHubContext{
+		APIURL:     srv.URL + "/api/v3",
+		GraphqlURL: "https://api.github.com/graphql",
+		Repository: "icinga/backbot",
+	}
+	client, err := NewClient(ghCtx, "token")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	projects, err := client.GetProjects(ctx, &github.PullRequest{Number: github.Ptr(1)})
+	require.NoError(t, err)
+	require.Equal(t, []Project{{ID: "PVT_1", Title: "Releases"}, {ID: "PVT_2", Title: "Roadmap"}}, projects)
+
+	require.NoError(t, client.AddToProject(ctx, &github.PullRequest{NodeID: github.Ptr("PR_2")}, projects[0]))
+	require.Equal(t, []map[string]any{{"project": "PVT_1", "content": "PR_2"}}, mutations)
+
+	err = client.AddToProject(ctx, &github.PullRequest{NodeID: github.Ptr("PR_3")}, projects[0])
+	require.EqualError(t, err, "Could not resolve to a node")
+
+	require.NoError(t, client.EnableAutoMerge(ctx, &github.PullRequest{NodeID: github.Ptr("PR_2")}, "squash"))
+	require.Equal(t, map[string]any{"pr": "PR_2", "method": "SQUASH"}, mutations[1])
+}
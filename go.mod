@@ -3,6 +3,7 @@ module github.com/yhabteab/backbot
 go 1.25
 
 require (
+	github.com/goccy/go-yaml v1.13.0
 	github.com/google/go-github/v75 v75.0.0
 	github.com/icinga/icinga-go-library v0.8.2
 	github.com/sethvargo/go-githubactions v1.3.2
@@ -14,7 +15,6 @@ require (
 	github.com/creasty/defaults v1.8.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fatih/color v1.18.0 // indirect
-	github.com/goccy/go-yaml v1.13.0 // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/jessevdk/go-flags v1.6.1 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
@@ -2,28 +2,42 @@ package main
 
 import (
 	"context"
+	"os"
 
 	"github.com/sethvargo/go-githubactions"
 	"github.com/yhabteab/backbot/backport"
 	"github.com/yhabteab/backbot/git"
+	"github.com/yhabteab/backbot/github"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "backport" {
+		runBackportCmd(os.Args[2:])
+		return
+	}
+
 	cfg, err := backport.LoadInputsFromEnv()
 	if err != nil {
 		githubactions.Fatalf("Failed to load inputs from environment: %v", err)
 	}
-	githubactions.AddMask(cfg.GitHubToken) // Mask the GitHub token in logs
+	if cfg.GitHubToken != "" {
+		githubactions.AddMask(cfg.GitHubToken) // Mask the GitHub token in logs
+	}
 
 	ghCtx, err := githubactions.Context()
 	if err != nil {
 		githubactions.Infof("Failed to retrieve GitHub context: %v", err)
 	}
-	if ghCtx.EventName != "pull_request" && ghCtx.EventName != "pull_request_target" {
-		githubactions.Fatalf("backbot only supports 'pull_request' and 'pull_request_target' events, got: %s", ghCtx.EventName)
+	switch ghCtx.EventName {
+	case github.EventPullRequest, github.EventPullRequestTarget, github.EventIssueComment:
+	default:
+		githubactions.Fatalf(
+			"backbot only supports 'pull_request', 'pull_request_target' and 'issue_comment' events, got: %s",
+			ghCtx.EventName,
+		)
 	}
 
-	if err := git.Configure(ghCtx, cfg.Committer, cfg.Email); err != nil {
+	if err := git.Configure(ghCtx, cfg.Committer, cfg.Email, true); err != nil {
 		githubactions.Fatalf("Failed to configure git: %v", err)
 	}
 	backport.Run(context.Background(), cfg, ghCtx)